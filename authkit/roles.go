@@ -0,0 +1,13 @@
+package authkit
+
+// Роли из claim'а roles access-токена. Единственный список имён для всех сервисов:
+// auth выдаёт роли из таблицы roles (заполняется в end-auth/config/schema.sql),
+// остальные сервисы проверяют их через RequireRoles.
+const (
+	// RoleAdmin — полный доступ, в том числе к /admin в auth.
+	RoleAdmin = "admin"
+	// RoleCoach — тренер: видит данные пользователей.
+	RoleCoach = "coach"
+	// RoleContentEditor — редактирует каталог упражнений.
+	RoleContentEditor = "content_editor"
+)
@@ -0,0 +1,331 @@
+// Package authkit — общий для сервисов код проверки токенов, выданных auth:
+// локально по JWKS или вызовом auth/api/v1/validate. Подключается через replace
+// на ../authkit, чтобы исправления попадали во все сервисы сразу.
+package authkit
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrInvalidToken    = errors.New("invalid token")
+	ErrAuthUnavailable = errors.New("auth unavailable")
+	ErrBadAuthResponse = errors.New("bad auth response")
+)
+
+const (
+	// ModeLocal — подпись и claims проверяются на месте по JWKS из auth.
+	ModeLocal = "local"
+	// ModeRemote — каждый запрос валидируется вызовом auth/api/v1/validate.
+	ModeRemote = "remote"
+)
+
+// Config — где искать auth и как проверять токены.
+type Config struct {
+	BaseURL string
+	Mode    string
+	Issuer  string
+	// JWKSTTL — как долго ключи из JWKS считаются свежими.
+	JWKSTTL time.Duration
+}
+
+// Principal — владелец проверенного access-токена: пользователь или, для токена
+// сервиса (client_credentials), клиент OAuth — тогда UserID пуст.
+type Principal struct {
+	UserID string
+	Roles  []string
+
+	ClientID string
+	Scopes   []string
+	// Scoped — персональный токен пользователя или токен стороннего приложения
+	// (тогда задан и ClientID): доступ ограничен Scopes.
+	Scoped bool
+}
+
+// IsService сообщает, что токен выдан сервису, а не пользователю.
+func (p Principal) IsService() bool { return p.UserID == "" && p.ClientID != "" }
+
+// TokenVerifier проверяет access-токен и возвращает его владельца.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (Principal, error)
+}
+
+// NewVerifier создаёт проверку токенов по cfg.Mode; по умолчанию — локальная.
+func NewVerifier(cfg Config) TokenVerifier {
+	client := &http.Client{
+		Timeout: 2 * time.Second,
+	}
+	authBase := strings.TrimRight(cfg.BaseURL, "/")
+
+	remote := &remoteVerifier{client: client, authBase: authBase}
+	if cfg.Mode == ModeRemote {
+		return remote
+	}
+	return &localVerifier{
+		remote:  remote,
+		client:  client,
+		jwksURL: authBase + "/.well-known/jwks.json",
+		issuer:  cfg.Issuer,
+		ttl:     cfg.JWKSTTL,
+	}
+}
+
+/* ========== remote: GET auth/api/v1/validate ========== */
+
+type remoteVerifier struct {
+	client   *http.Client
+	authBase string
+}
+
+type validateResponse struct {
+	TokenType string   `json:"token_type"`
+	UserID    string   `json:"user_id"`
+	Roles     []string `json:"roles"`
+	ClientID  string   `json:"client_id"`
+	Scopes    []string `json:"scopes"`
+}
+
+func (v *remoteVerifier) Verify(ctx context.Context, token string) (Principal, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.authBase+"/api/v1/validate", nil)
+	if err != nil {
+		return Principal{}, ErrAuthUnavailable
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return Principal{}, ErrAuthUnavailable
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Principal{}, ErrInvalidToken
+	}
+
+	var body validateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Principal{}, ErrBadAuthResponse
+	}
+	if body.TokenType == "service" && body.ClientID != "" {
+		return Principal{ClientID: body.ClientID, Scopes: body.Scopes}, nil
+	}
+	if body.UserID == "" {
+		return Principal{}, ErrBadAuthResponse
+	}
+	if body.TokenType == "personal" || body.TokenType == "app" {
+		return Principal{UserID: body.UserID, ClientID: body.ClientID, Scopes: body.Scopes, Scoped: true}, nil
+	}
+	return Principal{UserID: body.UserID, Roles: body.Roles}, nil
+}
+
+/* ========== local: подпись по JWKS из auth ========== */
+
+// jwksMinRefresh ограничивает перезапросы JWKS при токенах с неизвестным kid.
+const jwksMinRefresh = 30 * time.Second
+
+// personalTokenPrefix — персональные токены непрозрачны, их проверяет только auth.
+const personalTokenPrefix = "enp_"
+
+type localVerifier struct {
+	// remote проверяет персональные токены, которые нельзя проверить локально
+	remote  *remoteVerifier
+	client  *http.Client
+	jwksURL string
+	issuer  string
+	ttl     time.Duration
+
+	refreshMu sync.Mutex
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+func (v *localVerifier) Verify(ctx context.Context, token string) (Principal, error) {
+	if strings.HasPrefix(token, personalTokenPrefix) {
+		return v.remote.Verify(ctx, token)
+	}
+	t, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "EdDSA"}),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		if errors.Is(err, ErrAuthUnavailable) {
+			return Principal{}, ErrAuthUnavailable
+		}
+		return Principal{}, ErrInvalidToken
+	}
+
+	claims, ok := t.Claims.(jwt.MapClaims)
+	if !ok {
+		return Principal{}, ErrInvalidToken
+	}
+	if claims["typ"] == "service" {
+		// отзыв токенов сервисов виден только в auth; локально их ограничивает короткий срок жизни
+		clientID, _ := claims["client_id"].(string)
+		if clientID == "" {
+			return Principal{}, ErrInvalidToken
+		}
+		scope, _ := claims["scope"].(string)
+		return Principal{ClientID: clientID, Scopes: strings.Fields(scope)}, nil
+	}
+	if claims["typ"] != "access" {
+		return Principal{}, ErrInvalidToken
+	}
+	sub, _ := claims["sub"].(string)
+	if _, err := uuid.Parse(sub); err != nil {
+		return Principal{}, ErrInvalidToken
+	}
+	if clientID, _ := claims["client_id"].(string); clientID != "" {
+		// токен стороннего приложения: доступ только в пределах прав, на которые согласился пользователь
+		scope, _ := claims["scope"].(string)
+		return Principal{UserID: sub, ClientID: clientID, Scopes: strings.Fields(scope), Scoped: true}, nil
+	}
+
+	// при локальной проверке роли берутся из токена и обновляются только с refresh
+	var roles []string
+	list, _ := claims["roles"].([]interface{})
+	for _, r := range list {
+		if name, ok := r.(string); ok {
+			roles = append(roles, name)
+		}
+	}
+	return Principal{UserID: sub, Roles: roles}, nil
+}
+
+func (v *localVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.RLock()
+	k, ok := v.keys[kid]
+	fresh := time.Since(v.fetchedAt) < v.ttl
+	canRefresh := time.Since(v.fetchedAt) >= jwksMinRefresh
+	v.mu.RUnlock()
+
+	if ok && (fresh || !canRefresh) {
+		return k, nil
+	}
+	if !ok && !canRefresh {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+
+	if err := v.refresh(ctx); err != nil {
+		log.Warn().Err(err).Str("jwks_url", v.jwksURL).Msg("failed to refresh JWKS")
+		if ok {
+			// лучше проверить по закэшированному ключу, чем отказать всем
+			return k, nil
+		}
+		return nil, ErrAuthUnavailable
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if k, ok := v.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown kid %q", kid)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+}
+
+func (v *localVerifier) refresh(ctx context.Context) error {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+
+	v.mu.RLock()
+	justFetched := time.Since(v.fetchedAt) < jwksMinRefresh
+	v.mu.RUnlock()
+	if justFetched {
+		// ключи уже обновил параллельный запрос
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		pub, err := k.publicKey()
+		if err != nil {
+			log.Warn().Err(err).Str("kid", k.Kid).Msg("skipping unsupported JWK")
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+	return nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("bad Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported kty %q", k.Kty)
+	}
+}
@@ -32,6 +32,8 @@ func init() {
 // @securityDefinitions.apikey BearerAuth
 // @in              header
 // @name            Authorization
+
+// @securityDefinitions.basic ClientBasic
 func main() {
 	var cfg app.Config
 	cfgName := app.GetConfigName()
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"auth/internal/adapter/out/postgres"
+	"auth/internal/app"
+	"auth/internal/keys"
+	"auth/internal/password"
+	"auth/internal/service"
+
+	_ "github.com/joho/godotenv/autoload"
+	"github.com/num30/config"
+)
+
+const usage = `authctl — административные команды сервиса auth
+
+  authctl keys generate [-dir DIR] [-alg EdDSA|RS256] [-kid KID] [-activate-in 24h]
+      создать следующий ключ подписи и поставить его активацию в расписание
+      (для самого первого ключа используйте -activate-in 0)
+  authctl keys list [-dir DIR]
+      показать ключи и их состояние (pending/active/retiring/retired)
+  authctl keys prune [-dir DIR]
+      удалить ключи, которые уже вышли из ротации
+  authctl roles list -email EMAIL
+      показать роли пользователя
+  authctl roles grant -email EMAIL -role ROLE
+  authctl roles revoke -email EMAIL -role ROLE
+      выдать или забрать роль (так назначается первый admin)
+  authctl clients create -name NAME [-scopes trainings:read,user-info:read]
+                         [-redirect-uris URI,...] [-first-party] [-public]
+      зарегистрировать клиента OAuth; секрет печатается один раз.
+      -redirect-uris — для приложений, получающих доступ от имени пользователя;
+      -first-party — наше приложение, без экрана согласия (с секретом может отзывать любые токены); -public — без секрета (мобильное приложение)
+  authctl clients list
+  authctl clients disable -id CLIENT_ID
+  authctl passwords build-breached -in FILE [-format plain|sha1] [-out FILE] [-fp 0.001]
+      собрать фильтр утёкших паролей для svc.passwordPolicy.breachedFile.
+      -in — по паролю на строку или выгрузка SHA-1 Have I Been Pwned (HASH:COUNT);
+      фильтр занимает около 1.8 байта на пароль при -fp 0.001
+
+По умолчанию каталог и срок удержания берутся из конфига (APP_CONFIG_FILE).
+`
+
+func main() {
+	args := os.Args[1:]
+	if len(args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	// ConfReader разбирает os.Args как флаги конфига, поэтому свои аргументы прячем.
+	os.Args = os.Args[:1]
+	var cfg app.Config
+	if err := config.NewConfReader(app.GetConfigName()).WithPrefix("APP").Read(&cfg); err != nil {
+		fail(fmt.Errorf("failed to load config: %w", err))
+	}
+
+	switch args[0] + " " + args[1] {
+	case "keys generate":
+		keysGenerate(cfg, args[2:])
+	case "keys list":
+		keysList(cfg, args[2:])
+	case "keys prune":
+		keysPrune(cfg, args[2:])
+	case "roles list", "roles grant", "roles revoke":
+		roles(cfg, args[1], args[2:])
+	case "clients create", "clients list", "clients disable":
+		clients(cfg, args[1], args[2:])
+	case "passwords build-breached":
+		buildBreached(cfg, args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func keysGenerate(cfg app.Config, args []string) {
+	fs := flag.NewFlagSet("keys generate", flag.ExitOnError)
+	dir := fs.String("dir", cfg.Svc.Keyring.Dir, "каталог ключей")
+	alg := fs.String("alg", keys.AlgEdDSA, "алгоритм подписи: EdDSA или RS256")
+	kid := fs.String("kid", "", "идентификатор ключа (по умолчанию — по текущему времени)")
+	activateIn := fs.Duration("activate-in", 24*time.Hour,
+		"через сколько ключ начнёт подписывать токены; должно хватить, чтобы все сервисы обновили JWKS")
+	_ = fs.Parse(args)
+
+	if *dir == "" {
+		fail(fmt.Errorf("keyring dir is not set (svc.keyring.dir or -dir)"))
+	}
+	if *kid == "" {
+		*kid = "k-" + time.Now().UTC().Format("20060102T150405")
+	}
+
+	k, err := keys.Generate(*kid, *alg)
+	if err != nil {
+		fail(err)
+	}
+	activateAt := time.Now().Add(*activateIn).UTC().Truncate(time.Second)
+	if err := keys.AddToDir(*dir, k, activateAt); err != nil {
+		fail(err)
+	}
+	fmt.Printf("generated %s (%s), activates at %s\n", k.ID, *alg, activateAt.Format(time.RFC3339))
+}
+
+func keysList(cfg app.Config, args []string) {
+	fs := flag.NewFlagSet("keys list", flag.ExitOnError)
+	dir := fs.String("dir", cfg.Svc.Keyring.Dir, "каталог ключей")
+	_ = fs.Parse(args)
+
+	m, err := keys.ReadManifest(*dir)
+	if err != nil {
+		fail(err)
+	}
+	entries, err := keys.LoadDir(*dir)
+	if err != nil {
+		fail(err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("keyring is empty")
+		return
+	}
+	ring, err := keys.NewRing(cfg.Svc.KeyRetention(), entries)
+	if err != nil {
+		fail(err)
+	}
+	states := ring.States(time.Now())
+
+	sort.Slice(m.Keys, func(i, j int) bool { return m.Keys[i].ActivateAt.Before(m.Keys[j].ActivateAt) })
+	for _, me := range m.Keys {
+		fmt.Printf("%-24s %-6s %-9s %s\n", me.KID, me.Alg, states[me.KID], me.ActivateAt.Format(time.RFC3339))
+	}
+}
+
+func keysPrune(cfg app.Config, args []string) {
+	fs := flag.NewFlagSet("keys prune", flag.ExitOnError)
+	dir := fs.String("dir", cfg.Svc.Keyring.Dir, "каталог ключей")
+	_ = fs.Parse(args)
+
+	removed, err := keys.PruneDir(*dir, cfg.Svc.KeyRetention(), time.Now())
+	if err != nil {
+		fail(err)
+	}
+	for _, kid := range removed {
+		fmt.Printf("removed %s\n", kid)
+	}
+}
+
+func roles(cfg app.Config, cmd string, args []string) {
+	fs := flag.NewFlagSet("roles "+cmd, flag.ExitOnError)
+	email := fs.String("email", "", "email пользователя")
+	role := fs.String("role", "", "роль")
+	_ = fs.Parse(args)
+	if *email == "" || (cmd != "list" && *role == "") {
+		fail(fmt.Errorf("-email is required (and -role for grant/revoke)"))
+	}
+
+	db, repos, svc := openService(cfg)
+	defer db.Close()
+
+	ctx := context.Background()
+	u, err := repos.User.ByEmail(ctx, *email)
+	if err != nil {
+		fail(fmt.Errorf("user %s: %w", *email, err))
+	}
+
+	switch cmd {
+	case "grant":
+		err = svc.GrantRole(ctx, nil, u.ID, *role)
+	case "revoke":
+		err = svc.RevokeRole(ctx, nil, u.ID, *role)
+	}
+	if err != nil {
+		fail(err)
+	}
+
+	list, err := svc.UserRoles(ctx, u.ID)
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("%s %s: %s\n", u.ID, u.Email, strings.Join(list, ", "))
+}
+
+func clients(cfg app.Config, cmd string, args []string) {
+	fs := flag.NewFlagSet("clients "+cmd, flag.ExitOnError)
+	name := fs.String("name", "", "название клиента")
+	scopes := fs.String("scopes", "", "права через запятую, которые клиент может запросить")
+	redirectURIs := fs.String("redirect-uris", "", "разрешённые redirect_uri через запятую")
+	firstParty := fs.Bool("first-party", false, "наше приложение: согласие пользователя не спрашивается")
+	public := fs.Bool("public", false, "клиент без секрета (только authorization code + PKCE)")
+	id := fs.String("id", "", "client_id")
+	_ = fs.Parse(args)
+
+	db, _, svc := openService(cfg)
+	defer db.Close()
+	ctx := context.Background()
+
+	switch cmd {
+	case "create":
+		if *name == "" {
+			fail(fmt.Errorf("-name is required"))
+		}
+		c, secret, err := svc.CreateClient(ctx, service.ClientRegistration{
+			Name:         *name,
+			Scopes:       splitList(*scopes),
+			RedirectURIs: splitList(*redirectURIs),
+			FirstParty:   *firstParty,
+			Public:       *public,
+		})
+		if err != nil {
+			fail(err)
+		}
+		fmt.Printf("client_id:     %s\n", c.ID)
+		if secret != "" {
+			fmt.Printf("client_secret: %s\n", secret)
+			fmt.Println("сохраните секрет: повторно его показать нельзя")
+		}
+	case "list":
+		list, err := svc.ListClients(ctx)
+		if err != nil {
+			fail(err)
+		}
+		for _, c := range list {
+			state := "active"
+			if c.DisabledAt != nil {
+				state = "disabled"
+			}
+			kind := "confidential"
+			if c.Public() {
+				kind = "public"
+			}
+			if c.FirstParty {
+				kind += ",first-party"
+			}
+			fmt.Printf("%-24s %-9s %s  %s (%s) [%s]", c.ID, state, c.CreatedAt.Format(time.RFC3339), c.Name, kind, strings.Join(c.Scopes, " "))
+			if len(c.RedirectURIs) > 0 {
+				fmt.Printf(" -> %s", strings.Join(c.RedirectURIs, " "))
+			}
+			fmt.Println()
+		}
+	case "disable":
+		if *id == "" {
+			fail(fmt.Errorf("-id is required"))
+		}
+		if err := svc.DisableClient(ctx, *id); err != nil {
+			fail(fmt.Errorf("client %s: %w", *id, err))
+		}
+		fmt.Printf("disabled %s\n", *id)
+	}
+}
+
+func buildBreached(cfg app.Config, args []string) {
+	fs := flag.NewFlagSet("passwords build-breached", flag.ExitOnError)
+	in := fs.String("in", "", "файл корпуса")
+	format := fs.String("format", "plain", "plain — пароли, sha1 — hex SHA-1 (после двоеточия может идти счётчик)")
+	out := fs.String("out", cfg.Svc.PasswordPolicy.BreachedFile, "куда записать фильтр")
+	fp := fs.Float64("fp", 0.001, "доля ложных срабатываний")
+	_ = fs.Parse(args)
+	if *in == "" || *out == "" {
+		fail(fmt.Errorf("-in and -out are required"))
+	}
+	if *format != "plain" && *format != "sha1" {
+		fail(fmt.Errorf("unknown -format %q", *format))
+	}
+
+	// первый проход — считаем записи, чтобы подобрать размер фильтра
+	var n uint64
+	if err := eachLine(*in, func(string) { n++ }); err != nil {
+		fail(err)
+	}
+	bloom := password.NewBloom(n, *fp)
+	var skipped int
+	err := eachLine(*in, func(line string) {
+		if *format == "plain" {
+			bloom.Add(sha1.Sum([]byte(line)))
+			return
+		}
+		h, _, _ := strings.Cut(line, ":")
+		b, err := hex.DecodeString(strings.TrimSpace(h))
+		if err != nil || len(b) != sha1.Size {
+			skipped++
+			return
+		}
+		bloom.Add([sha1.Size]byte(b))
+	})
+	if err != nil {
+		fail(err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(*out), ".breached-*")
+	if err != nil {
+		fail(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := bloom.WriteTo(f); err != nil {
+		fail(err)
+	}
+	if err := f.Chmod(0o644); err != nil {
+		fail(err)
+	}
+	if err := f.Close(); err != nil {
+		fail(err)
+	}
+	if err := os.Rename(f.Name(), *out); err != nil {
+		fail(err)
+	}
+	fmt.Printf("wrote %s: %d passwords", *out, bloom.Len())
+	if skipped > 0 {
+		fmt.Printf(", %d malformed lines skipped", skipped)
+	}
+	fmt.Println()
+}
+
+// eachLine вызывает fn для каждой непустой строки файла.
+func eachLine(path string, fn func(string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		if line := sc.Text(); line != "" {
+			fn(line)
+		}
+	}
+	return sc.Err()
+}
+
+// openService собирает сервис без HTTP-сервера: почта, ключи подписи, провайдеры и корпус утёкших паролей здесь не нужны.
+func openService(cfg app.Config) (*sql.DB, *postgres.Repositories, *service.Service) {
+	db, err := sql.Open("postgres", cfg.DB.DSN)
+	if err != nil {
+		fail(err)
+	}
+	repos := postgres.NewRepositories(db)
+	svc := service.New(repos.User, repos.Refresh, repos.Revoked, repos.Reset, repos.Event, repos.Login, repos.MFA, repos.Role,
+		repos.Identity, repos.OIDC, nil, repos.Client, repos.Code, repos.Consent, repos.PAT, repos.Magic, nil, nil, nil, cfg.Svc)
+	return db, repos, svc
+}
+
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "error:", err)
+	os.Exit(1)
+}
@@ -2,59 +2,204 @@
 -- name: CreateUser :one
 INSERT INTO users (email, password_hash)
 VALUES ($1, $2)
-RETURNING id, email, password_hash, is_blocked, last_login_at, created_at, updated_at;
+RETURNING id, email, password_hash, is_blocked, last_login_at, email_verified_at, blocked_reason, blocked_until, must_reset_password, tokens_valid_after, created_at, updated_at;
 
 -- name: GetUserByEmail :one
-SELECT id, email, password_hash, is_blocked, last_login_at, created_at, updated_at
+SELECT id, email, password_hash, is_blocked, last_login_at, email_verified_at, blocked_reason, blocked_until, must_reset_password, tokens_valid_after, created_at, updated_at
 FROM users WHERE email = $1;
 
 -- name: GetUserByID :one
-SELECT id, email, password_hash, is_blocked, last_login_at, created_at, updated_at
+SELECT id, email, password_hash, is_blocked, last_login_at, email_verified_at, blocked_reason, blocked_until, must_reset_password, tokens_valid_after, created_at, updated_at
 FROM users WHERE id = $1;
 
+-- Прежний хэш уходит в password_history; в истории остаются keep_history последних.
 -- name: UpdatePassword :exec
-UPDATE users SET password_hash = $2, updated_at = now()
+WITH old AS (
+  SELECT users.id, users.password_hash FROM users WHERE users.id = sqlc.arg(id) FOR UPDATE
+), archived AS (
+  INSERT INTO password_history (user_id, password_hash)
+  SELECT old.id, old.password_hash FROM old
+  WHERE old.password_hash <> '' AND sqlc.arg(keep_history)::int > 0
+), trimmed AS (
+  -- вставка выше этому запросу не видна, поэтому из старых записей оставляем на одну меньше
+  DELETE FROM password_history
+  WHERE password_history.user_id = sqlc.arg(id)
+    AND password_history.id NOT IN (
+      SELECT ph.id FROM password_history ph WHERE ph.user_id = sqlc.arg(id)
+      ORDER BY ph.id DESC LIMIT GREATEST(sqlc.arg(keep_history)::int - 1, 0))
+)
+UPDATE users SET password_hash = sqlc.arg(password_hash), must_reset_password = false, updated_at = now()
+WHERE users.id = sqlc.arg(id);
+
+-- name: ListPasswordHistory :many
+SELECT password_hash FROM password_history
+WHERE user_id = $1
+ORDER BY id DESC
+LIMIT $2;
+
+-- Пересчёт хэша тем же паролем (смена алгоритма или параметров); не затирает
+-- хэш, если пароль успели сменить.
+-- name: RehashPassword :execrows
+UPDATE users SET password_hash = $3
+WHERE id = $1 AND password_hash = $2;
+
+-- name: SetTokensValidAfter :exec
+UPDATE users SET tokens_valid_after = $2, updated_at = now()
 WHERE id = $1;
 
 -- name: SetLastLogin :exec
 UPDATE users SET last_login_at = $2, updated_at = now()
 WHERE id = $1;
 
--- name: SetBlocked :exec
-UPDATE users SET is_blocked = $2, updated_at = now()
+-- name: MarkEmailVerified :execrows
+UPDATE users SET email_verified_at = now(), updated_at = now()
+WHERE id = $1 AND email = $2 AND email_verified_at IS NULL;
+
+-- Блокировка: закрывает предыдущую действующую запись истории и добавляет новую.
+-- name: BlockUser :exec
+WITH u AS (
+  UPDATE users
+  SET is_blocked = true, blocked_reason = sqlc.arg(reason), blocked_until = sqlc.narg(blocked_until), updated_at = now()
+  WHERE users.id = sqlc.arg(user_id)
+  RETURNING users.id
+), superseded AS (
+  UPDATE user_blocks
+  SET lifted_at = now(), lifted_by = sqlc.narg(actor_id), lift_reason = 'superseded'
+  WHERE user_blocks.user_id = sqlc.arg(user_id) AND lifted_at IS NULL
+    AND (user_blocks.blocked_until IS NULL OR user_blocks.blocked_until > now())
+)
+INSERT INTO user_blocks (user_id, actor_id, reason, blocked_until)
+SELECT u.id, sqlc.narg(actor_id), sqlc.arg(reason), sqlc.narg(blocked_until) FROM u;
+
+-- name: UnblockUser :exec
+WITH u AS (
+  UPDATE users
+  SET is_blocked = false, blocked_reason = NULL, blocked_until = NULL, updated_at = now()
+  WHERE users.id = sqlc.arg(user_id)
+  RETURNING users.id
+)
+UPDATE user_blocks
+SET lifted_at = now(), lifted_by = sqlc.narg(actor_id), lift_reason = sqlc.narg(lift_reason)
+WHERE user_blocks.user_id IN (SELECT u.id FROM u) AND lifted_at IS NULL
+  AND (user_blocks.blocked_until IS NULL OR user_blocks.blocked_until > now());
+
+-- name: ListUserBlocks :many
+SELECT id, user_id, actor_id, reason, blocked_at, blocked_until, lifted_at, lifted_by, lift_reason
+FROM user_blocks
+WHERE user_id = $1
+ORDER BY blocked_at DESC;
+
+-- name: RequirePasswordReset :exec
+UPDATE users SET must_reset_password = true, updated_at = now()
 WHERE id = $1;
 
+-- Поиск для админки: query — подстрока email (уже экранированная для LIKE).
+-- name: SearchUsers :many
+SELECT id, email, password_hash, is_blocked, last_login_at, email_verified_at, blocked_reason, blocked_until, must_reset_password, tokens_valid_after, created_at, updated_at
+FROM users
+WHERE (sqlc.narg(query)::text IS NULL OR email::text ILIKE '%' || sqlc.narg(query)::text || '%')
+  AND (sqlc.narg(blocked)::boolean IS NULL OR (is_blocked AND (blocked_until IS NULL OR blocked_until > now())) = sqlc.narg(blocked)::boolean)
+ORDER BY created_at DESC, id
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountUsers :one
+SELECT count(*)
+FROM users
+WHERE (sqlc.narg(query)::text IS NULL OR email::text ILIKE '%' || sqlc.narg(query)::text || '%')
+  AND (sqlc.narg(blocked)::boolean IS NULL OR (is_blocked AND (blocked_until IS NULL OR blocked_until > now())) = sqlc.narg(blocked)::boolean);
+
 -- ===== refresh_sessions =====
 -- name: CreateRefreshSession :one
-INSERT INTO refresh_sessions (user_id, token_hash, expires_at)
-VALUES ($1, $2, $3)
-RETURNING id, user_id, token_hash, user_agent, ip, created_at, expires_at, revoked_at;
+INSERT INTO refresh_sessions (user_id, family_id, parent_id, token_hash, user_agent, ip, device_name, client_id, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, user_id, family_id, parent_id, token_hash, user_agent, ip, device_name, client_id, scopes, created_at, expires_at, revoked_at;
 
 -- name: GetRefreshByHashActive :one
-SELECT id, user_id, token_hash, user_agent, ip, created_at, expires_at, revoked_at
+SELECT id, user_id, family_id, parent_id, token_hash, user_agent, ip, device_name, client_id, scopes, created_at, expires_at, revoked_at
 FROM refresh_sessions
 WHERE token_hash = $1
   AND revoked_at IS NULL
   AND now() < expires_at;
 
+-- name: GetRefreshByHash :one
+SELECT id, user_id, family_id, parent_id, token_hash, user_agent, ip, device_name, client_id, scopes, created_at, expires_at, revoked_at
+FROM refresh_sessions
+WHERE token_hash = $1;
+
+-- Сессия (семья) жива, пока у неё есть неотозванное и неистёкшее звено.
+-- name: IsRefreshFamilyActive :one
+SELECT EXISTS (
+  SELECT 1 FROM refresh_sessions
+  WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL AND now() < expires_at
+);
+
 -- name: RevokeRefreshByID :exec
 UPDATE refresh_sessions
 SET revoked_at = now()
 WHERE id = $1 AND revoked_at IS NULL;
 
+-- name: RotateRefreshByID :execrows
+UPDATE refresh_sessions
+SET revoked_at = now()
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshFamily :exec
+UPDATE refresh_sessions
+SET revoked_at = now()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshFamilyForUser :execrows
+UPDATE refresh_sessions
+SET revoked_at = now()
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- Активная сессия семьи — последнее звено цепочки; signed_in_at — время исходного логина.
+-- Сессии сторонних приложений сюда не входят, они видны в списке приложений.
+-- name: ListActiveRefreshForUser :many
+SELECT rs.family_id, rs.user_agent, rs.ip, rs.device_name, rs.created_at, rs.expires_at,
+       (SELECT min(f.created_at) FROM refresh_sessions f WHERE f.family_id = rs.family_id)::timestamptz AS signed_in_at
+FROM refresh_sessions rs
+WHERE rs.user_id = $1
+  AND rs.client_id IS NULL
+  AND rs.revoked_at IS NULL
+  AND now() < rs.expires_at
+ORDER BY rs.created_at DESC;
+
 -- name: RevokeAllRefreshForUser :exec
 UPDATE refresh_sessions
 SET revoked_at = now()
 WHERE user_id = $1 AND revoked_at IS NULL;
 
+-- name: RevokeOtherRefreshForUser :exec
+UPDATE refresh_sessions
+SET revoked_at = now()
+WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL;
+
+-- name: RevokeRefreshForUserClient :many
+UPDATE refresh_sessions
+SET revoked_at = now()
+WHERE user_id = $1 AND client_id = $2 AND revoked_at IS NULL
+RETURNING family_id;
+
+-- name: RevokeRefreshForClient :exec
+UPDATE refresh_sessions
+SET revoked_at = now()
+WHERE client_id = $1 AND revoked_at IS NULL;
+
 -- ===== password_resets (OTP) =====
+-- Новый код аннулирует все ранее выданные коды пользователя.
 -- name: CreatePasswordResetOTP :one
+WITH invalidated AS (
+  UPDATE password_resets
+  SET used_at = now()
+  WHERE user_id = $1 AND used_at IS NULL
+)
 INSERT INTO password_resets (user_id, otp_hash, expires_at)
 VALUES ($1, $2, $3)
-RETURNING id, user_id, otp_hash, expires_at, used_at;
+RETURNING id, user_id, otp_hash, expires_at, used_at, attempts, created_at;
 
 -- name: FindValidPasswordResetByUser :one
-SELECT id, user_id, otp_hash, expires_at, used_at
+SELECT id, user_id, otp_hash, expires_at, used_at, attempts, created_at
 FROM password_resets
 WHERE user_id = $1
   AND used_at IS NULL
@@ -62,7 +207,330 @@ WHERE user_id = $1
 ORDER BY expires_at DESC
 LIMIT 1;
 
--- name: MarkPasswordResetUsed :exec
+-- name: GetLatestPasswordResetByUser :one
+SELECT id, user_id, otp_hash, expires_at, used_at, attempts, created_at
+FROM password_resets
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- Попытка засчитывается до проверки кода, атомарно: параллельные запросы не
+-- получат больше max_attempts проверок. Нет строки — код использован, истёк или попытки исчерпаны.
+-- name: ClaimPasswordResetAttempt :one
+UPDATE password_resets
+SET attempts = attempts + 1
+WHERE id = sqlc.arg(id)
+  AND used_at IS NULL
+  AND now() < expires_at
+  AND attempts < sqlc.arg(max_attempts)::int
+RETURNING id, user_id, otp_hash, expires_at, used_at, attempts, created_at;
+
+-- name: MarkPasswordResetUsed :execrows
 UPDATE password_resets
 SET used_at = now()
 WHERE id = $1 AND used_at IS NULL;
+
+-- ===== magic_links =====
+-- name: CreateMagicLink :one
+INSERT INTO magic_links (id, user_id, expires_at)
+VALUES ($1, $2, $3)
+RETURNING id, user_id, created_at, expires_at, consumed_at;
+
+-- Ссылкой можно войти один раз и только до истечения.
+-- name: ConsumeMagicLink :one
+UPDATE magic_links
+SET consumed_at = now()
+WHERE id = $1 AND consumed_at IS NULL AND now() < expires_at
+RETURNING id, user_id, created_at, expires_at, consumed_at;
+
+-- name: GetLatestMagicLinkByUser :one
+SELECT id, user_id, created_at, expires_at, consumed_at
+FROM magic_links
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: DeleteExpiredMagicLinks :execrows
+DELETE FROM magic_links
+WHERE expires_at < now() - interval '1 day';
+
+-- ===== auth_events =====
+-- name: CreateAuthEvent :exec
+INSERT INTO auth_events (user_id, actor_id, type, ip, user_agent, details)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ListUserAuthEvents :many
+SELECT id, user_id, actor_id, type, ip, user_agent, details, created_at
+FROM auth_events
+WHERE user_id = $1
+ORDER BY id DESC
+LIMIT $2;
+
+-- Поиск для админки; страницы листаются курсором before_id (id последней записи предыдущей страницы).
+-- name: SearchAuthEvents :many
+SELECT id, user_id, actor_id, type, ip, user_agent, details, created_at
+FROM auth_events
+WHERE (sqlc.narg(user_id)::uuid IS NULL OR user_id = sqlc.narg(user_id)::uuid)
+  AND (sqlc.narg(actor_id)::uuid IS NULL OR actor_id = sqlc.narg(actor_id)::uuid)
+  AND (cardinality(sqlc.arg(types)::text[]) = 0 OR type = ANY(sqlc.arg(types)::text[]))
+  AND (sqlc.narg(ip)::inet IS NULL OR ip <<= sqlc.narg(ip)::inet)
+  AND (sqlc.narg(since)::timestamptz IS NULL OR created_at >= sqlc.narg(since)::timestamptz)
+  AND (sqlc.narg(until)::timestamptz IS NULL OR created_at < sqlc.narg(until)::timestamptz)
+  AND (sqlc.narg(before_id)::bigint IS NULL OR id < sqlc.narg(before_id)::bigint)
+ORDER BY id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- ===== login_failures =====
+-- name: GetLoginFailures :many
+SELECT key, failures, last_failed_at, locked_until
+FROM login_failures
+WHERE key = ANY(sqlc.arg(keys)::text[]);
+
+-- Счётчик начинается заново, если с прошлой неудачи прошло больше окна (reset_before).
+-- name: RecordLoginFailure :one
+INSERT INTO login_failures (key, failures, last_failed_at)
+VALUES (sqlc.arg(key), 1, sqlc.arg(now))
+ON CONFLICT (key) DO UPDATE
+SET failures = CASE
+      WHEN login_failures.last_failed_at < sqlc.arg(reset_before) THEN 1
+      ELSE login_failures.failures + 1
+    END,
+    last_failed_at = sqlc.arg(now)
+RETURNING failures;
+
+-- name: SetLoginLockedUntil :exec
+UPDATE login_failures SET locked_until = $2
+WHERE key = $1;
+
+-- name: ClearLoginFailures :exec
+DELETE FROM login_failures WHERE key = $1;
+
+-- Счётчики, которые и так начались бы заново; действующую блокировку не трогаем.
+-- name: DeleteStaleLoginFailures :execrows
+DELETE FROM login_failures
+WHERE last_failed_at < $1
+  AND (locked_until IS NULL OR locked_until < now());
+
+-- ===== user_mfa =====
+-- Начать (или начать заново) регистрацию аутентификатора; подтверждённую 2FA не трогает.
+-- name: StartMFAEnrollment :execrows
+INSERT INTO user_mfa (user_id, totp_secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET totp_secret = EXCLUDED.totp_secret,
+    last_used_step = 0,
+    created_at = now()
+WHERE user_mfa.confirmed_at IS NULL;
+
+-- name: GetUserMFA :one
+SELECT user_id, totp_secret, confirmed_at, last_used_step, created_at
+FROM user_mfa
+WHERE user_id = $1;
+
+-- name: ConfirmUserMFA :execrows
+UPDATE user_mfa
+SET confirmed_at = now(), last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NULL;
+
+-- name: UseTOTPStep :execrows
+UPDATE user_mfa
+SET last_used_step = $2
+WHERE user_id = $1 AND last_used_step < $2;
+
+-- name: DeleteUserMFA :exec
+WITH codes AS (
+  DELETE FROM mfa_recovery_codes WHERE user_id = $1
+)
+DELETE FROM user_mfa WHERE user_mfa.user_id = $1;
+
+-- ===== mfa_recovery_codes =====
+-- name: ReplaceRecoveryCodes :exec
+WITH old AS (
+  DELETE FROM mfa_recovery_codes WHERE user_id = sqlc.arg(user_id)
+)
+INSERT INTO mfa_recovery_codes (user_id, code_hash)
+SELECT sqlc.arg(user_id), unnest(sqlc.arg(code_hashes)::bytea[]);
+
+-- name: UseRecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = now()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT count(*) FROM mfa_recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- ===== user_identities =====
+-- name: GetIdentity :one
+SELECT id, user_id, provider, subject, email, created_at, last_login_at
+FROM user_identities
+WHERE provider = $1 AND subject = $2;
+
+-- name: CreateIdentity :one
+INSERT INTO user_identities (user_id, provider, subject, email)
+VALUES ($1, $2, $3, $4)
+RETURNING id, user_id, provider, subject, email, created_at, last_login_at;
+
+-- name: TouchIdentity :exec
+UPDATE user_identities
+SET last_login_at = now(), email = $2
+WHERE id = $1;
+
+-- name: ListIdentitiesByUser :many
+SELECT id, user_id, provider, subject, email, created_at, last_login_at
+FROM user_identities
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- ===== oidc_auth_requests =====
+-- name: CreateOIDCRequest :exec
+INSERT INTO oidc_auth_requests (state, provider, nonce, code_verifier, return_to, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- state одноразовый: после callback'а строка ждёт только обмена login_code.
+-- name: ConsumeOIDCState :one
+UPDATE oidc_auth_requests
+SET consumed_at = now()
+WHERE state = $1 AND provider = $2 AND consumed_at IS NULL AND now() < expires_at
+RETURNING state, provider, nonce, code_verifier, return_to, user_id, login_code_hash, consumed_at, created_at, expires_at;
+
+-- name: SetOIDCLoginCode :exec
+UPDATE oidc_auth_requests
+SET user_id = $2, login_code_hash = $3, expires_at = $4
+WHERE state = $1;
+
+-- name: ConsumeOIDCLoginCode :one
+DELETE FROM oidc_auth_requests
+WHERE login_code_hash = $1 AND now() < expires_at
+RETURNING state, provider, nonce, code_verifier, return_to, user_id, login_code_hash, consumed_at, created_at, expires_at;
+
+-- name: DeleteExpiredOIDCRequests :execrows
+DELETE FROM oidc_auth_requests WHERE expires_at < now();
+
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (jti, user_id, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: IsTokenRevoked :one
+SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1);
+
+-- name: DeleteExpiredRevokedTokens :execrows
+DELETE FROM revoked_tokens WHERE expires_at < now();
+
+-- ===== oauth_clients =====
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, name, secret_hash, scopes, redirect_uris, first_party)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, name, secret_hash, scopes, redirect_uris, first_party, created_at, disabled_at;
+
+-- name: GetOAuthClient :one
+SELECT id, name, secret_hash, scopes, redirect_uris, first_party, created_at, disabled_at
+FROM oauth_clients WHERE id = $1;
+
+-- name: ListOAuthClients :many
+SELECT id, name, secret_hash, scopes, redirect_uris, first_party, created_at, disabled_at
+FROM oauth_clients
+ORDER BY created_at;
+
+-- name: DisableOAuthClient :execrows
+UPDATE oauth_clients SET disabled_at = now()
+WHERE id = $1 AND disabled_at IS NULL;
+
+-- ===== oauth_authorization_codes =====
+-- name: CreateAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- Код можно обменять один раз и только до истечения.
+-- name: ConsumeAuthorizationCode :one
+UPDATE oauth_authorization_codes
+SET consumed_at = now()
+WHERE code_hash = $1 AND consumed_at IS NULL AND now() < expires_at
+RETURNING code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, family_id, created_at, expires_at, consumed_at;
+
+-- name: GetAuthorizationCode :one
+SELECT code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, family_id, created_at, expires_at, consumed_at
+FROM oauth_authorization_codes
+WHERE code_hash = $1;
+
+-- name: SetAuthorizationCodeFamily :exec
+UPDATE oauth_authorization_codes SET family_id = $2
+WHERE code_hash = $1;
+
+-- Коды хранятся ещё сутки после истечения, чтобы распознать повторное предъявление.
+-- name: DeleteExpiredAuthorizationCodes :execrows
+DELETE FROM oauth_authorization_codes
+WHERE expires_at < now() - interval '1 day';
+
+-- ===== oauth_consents =====
+-- Новое согласие дополняет уже выданные права.
+-- name: UpsertOAuthConsent :exec
+INSERT INTO oauth_consents (user_id, client_id, scopes)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, client_id) DO UPDATE
+SET scopes = ARRAY(SELECT DISTINCT s FROM unnest(oauth_consents.scopes || EXCLUDED.scopes) AS s ORDER BY s),
+    updated_at = now();
+
+-- name: GetOAuthConsent :one
+SELECT user_id, client_id, scopes, created_at, updated_at
+FROM oauth_consents
+WHERE user_id = $1 AND client_id = $2;
+
+-- name: ListOAuthConsentsForUser :many
+SELECT oc.client_id, c.name AS client_name, oc.scopes, oc.created_at, oc.updated_at
+FROM oauth_consents oc
+JOIN oauth_clients c ON c.id = oc.client_id
+WHERE oc.user_id = $1
+ORDER BY oc.updated_at DESC;
+
+-- name: DeleteOAuthConsent :execrows
+DELETE FROM oauth_consents
+WHERE user_id = $1 AND client_id = $2;
+
+-- ===== personal_access_tokens =====
+-- name: CreatePersonalToken :one
+INSERT INTO personal_access_tokens (user_id, name, token_hash, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at, revoked_at;
+
+-- name: GetActivePersonalToken :one
+SELECT id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at, revoked_at
+FROM personal_access_tokens
+WHERE token_hash = $1 AND revoked_at IS NULL AND now() < expires_at;
+
+-- Неотозванные токены, включая истёкшие, — чтобы пользователь видел, что пора выпустить новый.
+-- name: ListPersonalTokens :many
+SELECT id, user_id, name, token_hash, scopes, expires_at, last_used_at, created_at, revoked_at
+FROM personal_access_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: RevokePersonalToken :execrows
+UPDATE personal_access_tokens SET revoked_at = now()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- last_used_at обновляется не чаще раза в минуту, чтобы не писать в базу на каждый запрос.
+-- name: TouchPersonalToken :exec
+UPDATE personal_access_tokens SET last_used_at = now()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
+
+-- name: ListRoles :many
+SELECT name, description, created_at
+FROM roles
+ORDER BY name;
+
+-- name: ListUserRoles :many
+SELECT role
+FROM user_roles
+WHERE user_id = $1
+ORDER BY role;
+
+-- name: GrantRole :execrows
+INSERT INTO user_roles (user_id, role, granted_by)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, role) DO NOTHING;
+
+-- name: RevokeRole :execrows
+DELETE FROM user_roles
+WHERE user_id = $1 AND role = $2;
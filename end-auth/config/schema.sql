@@ -2,15 +2,33 @@ CREATE EXTENSION IF NOT EXISTS pgcrypto;
 CREATE EXTENSION IF NOT EXISTS citext;
 
 CREATE TABLE IF NOT EXISTS users (
-  id             UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
-  email          CITEXT      UNIQUE NOT NULL,
-  password_hash  TEXT        NOT NULL,
-  is_blocked     BOOLEAN     NOT NULL DEFAULT false,
-  last_login_at  TIMESTAMPTZ,
-  created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
-  updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+  id                   UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+  email                CITEXT      UNIQUE NOT NULL,
+  password_hash        TEXT        NOT NULL,
+  is_blocked           BOOLEAN     NOT NULL DEFAULT false,
+  last_login_at        TIMESTAMPTZ,
+  email_verified_at    TIMESTAMPTZ,
+  -- blocked_reason — почему администратор заблокировал аккаунт (для оператора, пользователю не показывается).
+  blocked_reason       TEXT,
+  -- blocked_until — конец временной блокировки; NULL при is_blocked — бессрочная. Истёкшая снимается сама.
+  blocked_until        TIMESTAMPTZ,
+  -- must_reset_password — администратор потребовал сменить пароль; вход закрыт до сброса по коду.
+  must_reset_password  BOOLEAN     NOT NULL DEFAULT false,
+  -- tokens_valid_after — access-токены, выпущенные раньше, недействительны (выход везде, смена пароля).
+  tokens_valid_after   TIMESTAMPTZ,
+  created_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at           TIMESTAMPTZ NOT NULL DEFAULT now()
 );
 
+-- Схема применяется и к уже существующей базе (psql -f config/schema.sql):
+-- CREATE TABLE IF NOT EXISTS не трогает старые таблицы, поэтому новые колонки
+-- добавляются отдельно после каждой таблицы.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS blocked_reason TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS blocked_until TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS must_reset_password BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_valid_after TIMESTAMPTZ;
+
 CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at);
 
 CREATE OR REPLACE FUNCTION set_updated_at() RETURNS trigger AS $$
@@ -19,30 +37,292 @@ DROP TRIGGER IF EXISTS trg_users_updated_at ON users;
 CREATE TRIGGER trg_users_updated_at BEFORE UPDATE ON users
 FOR EACH ROW EXECUTE FUNCTION set_updated_at();
 
+-- Клиенты OAuth: сервисы и интеграции, которые обращаются к /oauth/*.
+-- Секрет показывается один раз при создании, хранится только его SHA-256;
+-- у публичных клиентов (мобильные приложения) секрета нет — код защищён PKCE.
+-- scopes — что клиент может запросить (client_credentials или от имени пользователя),
+-- redirect_uris — куда можно вернуть код авторизации; first_party — наши приложения,
+-- им не нужно согласие пользователя, а с секретом они могут отзывать любые токены (/oauth/revoke).
+CREATE TABLE IF NOT EXISTS oauth_clients (
+  id             TEXT        PRIMARY KEY,
+  name           TEXT        NOT NULL,
+  secret_hash    BYTEA,
+  scopes         TEXT[]      NOT NULL DEFAULT '{}',
+  redirect_uris  TEXT[]      NOT NULL DEFAULT '{}',
+  first_party    BOOLEAN     NOT NULL DEFAULT false,
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+  disabled_at    TIMESTAMPTZ
+);
+
+ALTER TABLE oauth_clients ALTER COLUMN secret_hash DROP NOT NULL;
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS scopes TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS redirect_uris TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE oauth_clients ADD COLUMN IF NOT EXISTS first_party BOOLEAN NOT NULL DEFAULT false;
+
+-- family_id объединяет цепочку ротаций одного логина, parent_id — сессия, из которой выпущена эта.
+-- client_id заполнен у сессий сторонних приложений (OAuth): их права ограничены scopes.
 CREATE TABLE IF NOT EXISTS refresh_sessions (
   id          UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
   user_id     UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  family_id   UUID        NOT NULL,
+  parent_id   UUID        REFERENCES refresh_sessions(id) ON DELETE SET NULL,
   token_hash  BYTEA       NOT NULL,
   user_agent  TEXT,
   ip          INET,
+  device_name TEXT,
+  client_id   TEXT        REFERENCES oauth_clients(id) ON DELETE CASCADE,
+  scopes      TEXT[],
   created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
   expires_at  TIMESTAMPTZ NOT NULL,
   revoked_at  TIMESTAMPTZ
 );
 
+-- Старые сессии становятся семьями из одной сессии.
+ALTER TABLE refresh_sessions ADD COLUMN IF NOT EXISTS family_id UUID;
+UPDATE refresh_sessions SET family_id = id WHERE family_id IS NULL;
+ALTER TABLE refresh_sessions ALTER COLUMN family_id SET NOT NULL;
+ALTER TABLE refresh_sessions ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES refresh_sessions(id) ON DELETE SET NULL;
+ALTER TABLE refresh_sessions ADD COLUMN IF NOT EXISTS device_name TEXT;
+ALTER TABLE refresh_sessions ADD COLUMN IF NOT EXISTS client_id TEXT REFERENCES oauth_clients(id) ON DELETE CASCADE;
+ALTER TABLE refresh_sessions ADD COLUMN IF NOT EXISTS scopes TEXT[];
+
 CREATE UNIQUE INDEX IF NOT EXISTS uq_refresh_token_hash ON refresh_sessions(token_hash);
 CREATE INDEX IF NOT EXISTS idx_refresh_user ON refresh_sessions(user_id);
 CREATE INDEX IF NOT EXISTS idx_refresh_active ON refresh_sessions(user_id)
   WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_refresh_family ON refresh_sessions(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_client ON refresh_sessions(client_id)
+  WHERE client_id IS NOT NULL;
 
+-- used_at ставится и при использовании кода, и когда он аннулирован (новый код, исчерпаны попытки).
 CREATE TABLE IF NOT EXISTS password_resets (
   id          UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
   user_id     UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
   otp_hash    TEXT        NOT NULL,
   expires_at  TIMESTAMPTZ NOT NULL,
-  used_at     TIMESTAMPTZ
+  used_at     TIMESTAMPTZ,
+  attempts    INT         NOT NULL DEFAULT 0,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
 );
 
+ALTER TABLE password_resets ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE password_resets ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
 CREATE INDEX IF NOT EXISTS idx_pwreset_user ON password_resets(user_id);
 CREATE INDEX IF NOT EXISTS idx_pwreset_active ON password_resets(user_id)
   WHERE used_at IS NULL;
+
+-- Хэши прежних паролей для запрета повторов (svc.passwordPolicy.history).
+CREATE TABLE IF NOT EXISTS password_history (
+  id             BIGSERIAL   PRIMARY KEY,
+  user_id        UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  password_hash  TEXT        NOT NULL,
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_history_user ON password_history(user_id, id DESC);
+
+-- Ссылки для входа без пароля. id — jti подписанного токена из письма; ссылка
+-- одноразовая: consumed_at ставится при входе, повторный переход отклоняется.
+CREATE TABLE IF NOT EXISTS magic_links (
+  id           UUID        PRIMARY KEY,
+  user_id      UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at   TIMESTAMPTZ NOT NULL,
+  consumed_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_magic_links_user ON magic_links(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_magic_links_expires ON magic_links(expires_at);
+
+-- Журнал безопасности, только на добавление. actor_id — кто выполнил действие,
+-- если не сам пользователь (администратор); ip и user_agent — откуда пришёл запрос.
+CREATE TABLE IF NOT EXISTS auth_events (
+  id          BIGSERIAL   PRIMARY KEY,
+  user_id     UUID        REFERENCES users(id) ON DELETE SET NULL,
+  actor_id    UUID        REFERENCES users(id) ON DELETE SET NULL,
+  type        TEXT        NOT NULL,
+  ip          INET,
+  user_agent  TEXT,
+  details     JSONB       NOT NULL DEFAULT '{}',
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE auth_events ADD COLUMN IF NOT EXISTS actor_id UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE auth_events ADD COLUMN IF NOT EXISTS ip INET;
+ALTER TABLE auth_events ADD COLUMN IF NOT EXISTS user_agent TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_auth_events_user ON auth_events(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_auth_events_created ON auth_events(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_auth_events_type ON auth_events(type, created_at DESC);
+
+-- Записи журнала нельзя менять и удалять; разрешено только обнулить ссылки
+-- на пользователей (ON DELETE SET NULL при удалении аккаунта).
+CREATE OR REPLACE FUNCTION auth_events_append_only() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'UPDATE'
+     AND NEW.id = OLD.id AND NEW.type = OLD.type AND NEW.details = OLD.details
+     AND NEW.created_at = OLD.created_at
+     AND NEW.ip IS NOT DISTINCT FROM OLD.ip AND NEW.user_agent IS NOT DISTINCT FROM OLD.user_agent
+     AND (NEW.user_id IS NULL OR NEW.user_id = OLD.user_id)
+     AND (NEW.actor_id IS NULL OR NEW.actor_id = OLD.actor_id) THEN
+    RETURN NEW;
+  END IF;
+  RAISE EXCEPTION 'auth_events is append-only';
+END; $$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS trg_auth_events_append_only ON auth_events;
+CREATE TRIGGER trg_auth_events_append_only BEFORE UPDATE OR DELETE ON auth_events
+FOR EACH ROW EXECUTE FUNCTION auth_events_append_only();
+
+-- Счётчики неудачных логинов; key — "account:<email>" или "ip:<адрес>".
+CREATE TABLE IF NOT EXISTS login_failures (
+  key             TEXT        PRIMARY KEY,
+  failures        INT         NOT NULL DEFAULT 0,
+  last_failed_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  locked_until    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_failures_last ON login_failures(last_failed_at);
+
+-- TOTP-аутентификатор пользователя. confirmed_at IS NULL — регистрация не завершена, 2FA выключена.
+-- last_used_step — последний принятый 30-секундный интервал, защищает от повторного ввода кода.
+CREATE TABLE IF NOT EXISTS user_mfa (
+  user_id         UUID        PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  totp_secret     TEXT        NOT NULL,
+  confirmed_at    TIMESTAMPTZ,
+  last_used_step  BIGINT      NOT NULL DEFAULT 0,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+  id          UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id     UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash   BYTEA       NOT NULL,
+  used_at     TIMESTAMPTZ,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_mfa_recovery_code ON mfa_recovery_codes(user_id, code_hash);
+
+-- Внешние аккаунты (OIDC), привязанные к пользователю. Пользователь, созданный через
+-- провайдера, может не иметь пароля (users.password_hash = '').
+CREATE TABLE IF NOT EXISTS user_identities (
+  id             UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id        UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  provider       TEXT        NOT NULL,
+  subject        TEXT        NOT NULL,
+  email          CITEXT,
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_login_at  TIMESTAMPTZ,
+  UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities(user_id);
+
+-- Незавершённые OIDC-входы: state/nonce/PKCE до callback'а, затем одноразовый
+-- login_code, который фронтенд обменивает на токены.
+CREATE TABLE IF NOT EXISTS oidc_auth_requests (
+  state            TEXT        PRIMARY KEY,
+  provider         TEXT        NOT NULL,
+  nonce            TEXT        NOT NULL,
+  code_verifier    TEXT        NOT NULL,
+  return_to        TEXT        NOT NULL,
+  user_id          UUID        REFERENCES users(id) ON DELETE CASCADE,
+  login_code_hash  BYTEA       UNIQUE,
+  consumed_at      TIMESTAMPTZ,
+  created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at       TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_requests_expires ON oidc_auth_requests(expires_at);
+
+-- Отозванные до истечения access-токены. Строка нужна только до expires_at —
+-- дальше токен отвергается и так.
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+  jti         TEXT        PRIMARY KEY,
+  user_id     UUID        REFERENCES users(id) ON DELETE CASCADE,
+  expires_at  TIMESTAMPTZ NOT NULL,
+  revoked_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires ON revoked_tokens(expires_at);
+
+-- Персональные токены доступа для скриптов и интеграций. Токен показывается
+-- один раз, хранится только SHA-256; права ограничены scopes.
+CREATE TABLE IF NOT EXISTS personal_access_tokens (
+  id            UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id       UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name          TEXT        NOT NULL,
+  token_hash    BYTEA       NOT NULL UNIQUE,
+  scopes        TEXT[]      NOT NULL DEFAULT '{}',
+  expires_at    TIMESTAMPTZ NOT NULL,
+  last_used_at  TIMESTAMPTZ,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  revoked_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_pat_user ON personal_access_tokens(user_id, created_at DESC);
+
+-- Коды авторизации (authorization code + PKCE). Код одноразовый; family_id — сессия,
+-- выпущенная по коду: при повторном предъявлении кода она отзывается.
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+  code_hash       BYTEA       PRIMARY KEY,
+  client_id       TEXT        NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+  user_id         UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  redirect_uri    TEXT        NOT NULL,
+  scopes          TEXT[]      NOT NULL DEFAULT '{}',
+  code_challenge  TEXT        NOT NULL,
+  family_id       UUID,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at      TIMESTAMPTZ NOT NULL,
+  consumed_at     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_codes_expires ON oauth_authorization_codes(expires_at);
+
+-- Согласия пользователей: каким сторонним приложениям и с какими правами выдан доступ.
+CREATE TABLE IF NOT EXISTS oauth_consents (
+  user_id     UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  client_id   TEXT        NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+  scopes      TEXT[]      NOT NULL DEFAULT '{}',
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, client_id)
+);
+
+-- История блокировок. lifted_* заполняются, когда блокировку сняли досрочно
+-- (администратор или новая блокировка); истёкшая по blocked_until остаётся как есть.
+CREATE TABLE IF NOT EXISTS user_blocks (
+  id             UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id        UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  actor_id       UUID        REFERENCES users(id) ON DELETE SET NULL,
+  reason         TEXT        NOT NULL,
+  blocked_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+  blocked_until  TIMESTAMPTZ,
+  lifted_at      TIMESTAMPTZ,
+  lifted_by      UUID        REFERENCES users(id) ON DELETE SET NULL,
+  lift_reason    TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_blocks_user ON user_blocks(user_id, blocked_at DESC);
+
+-- Роли выдаются пользователям и попадают в access-токен claim'ом roles.
+CREATE TABLE IF NOT EXISTS roles (
+  name         TEXT        PRIMARY KEY,
+  description  TEXT        NOT NULL DEFAULT '',
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO roles (name, description) VALUES
+  ('admin', 'Администратор: управление пользователями и ролями'),
+  ('coach', 'Тренер'),
+  ('content_editor', 'Редактор каталога упражнений и глобальных тренировок')
+ON CONFLICT (name) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS user_roles (
+  user_id     UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role        TEXT        NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+  granted_by  UUID        REFERENCES users(id) ON DELETE SET NULL,
+  granted_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, role)
+);
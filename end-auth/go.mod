@@ -3,6 +3,7 @@ module auth
 go 1.25.0
 
 require (
+	github.com/EnduranNSU/authkit v0.0.0
 	github.com/gin-gonic/gin v1.11.0
 	github.com/golang-jwt/jwt/v5 v5.3.0
 	github.com/google/uuid v1.6.0
@@ -86,3 +87,6 @@ require (
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+// authkit лежит рядом в монорепозитории
+replace github.com/EnduranNSU/authkit => ../authkit
@@ -0,0 +1,315 @@
+package httpin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"auth/internal/adapter/in/http/dto"
+	"auth/internal/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// AdminListUsers ищет пользователей
+// @Summary      Пользователи
+// @Description  Только для admin. Поиск по подстроке email, фильтр по блокировке, сортировка — сначала новые.
+// @Tags         admin
+// @Produce      json
+// @Param        q        query     string  false  "Подстрока email"
+// @Param        blocked  query     bool    false  "Только заблокированные (true) или только активные (false)"
+// @Param        limit    query     int     false  "Размер страницы (по умолчанию 50, максимум 200)"
+// @Param        offset   query     int     false  "Смещение"
+// @Success      200      {object}  dto.AdminUserListResponse
+// @Failure      400      {object}  dto.ErrorResponse  "Некорректный blocked"
+// @Failure      401      {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      403      {object}  dto.ErrorResponse  "Нет роли admin"
+// @Failure      500      {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/users [get]
+func (h *AuthHandler) AdminListUsers(c *gin.Context) {
+	f := domain.UserFilter{Query: c.Query("q")}
+	if v := c.Query("blocked"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+			return
+		}
+		f.Blocked = &b
+	}
+	f.Limit, _ = strconv.Atoi(c.Query("limit"))
+	f.Offset, _ = strconv.Atoi(c.Query("offset"))
+
+	page, err := h.svc.ListUsers(c.Request.Context(), f)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		return
+	}
+
+	resp := dto.AdminUserListResponse{
+		Users:  make([]dto.AdminUserResponse, 0, len(page.Users)),
+		Total:  page.Total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+	for _, u := range page.Users {
+		resp.Users = append(resp.Users, toAdminUser(u))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// AdminGetUser возвращает карточку пользователя
+// @Summary      Карточка пользователя
+// @Description  Только для admin
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "ID пользователя"
+// @Success      200  {object}  dto.AdminUserDetailsResponse
+// @Failure      400  {object}  dto.ErrorResponse  "Некорректный ID"
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      403  {object}  dto.ErrorResponse  "Нет роли admin"
+// @Failure      404  {object}  dto.ErrorResponse  "Пользователь не найден"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/users/{id} [get]
+func (h *AuthHandler) AdminGetUser(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+
+	d, err := h.svc.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		abortAdminError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.AdminUserDetailsResponse{
+		AdminUserResponse: toAdminUser(d.User),
+		Roles:             nonNil(d.Roles),
+		MFAEnabled:        d.MFAEnabled,
+	})
+}
+
+// AdminBlockUser блокирует пользователя
+// @Summary      Заблокировать
+// @Description  Только для admin. Без until блокировка бессрочная, с until — снимается сама. Завершает все сессии пользователя; уже выданные access-токены перестают проходить /validate.
+// @Tags         admin
+// @Accept       json
+// @Param        id       path      string                true  "ID пользователя"
+// @Param        request  body      dto.BlockUserRequest  true  "Причина"
+// @Success      204      {string}  string             "Пользователь заблокирован, тело отсутствует"
+// @Failure      400      {object}  dto.ErrorResponse  "Некорректный ID, нет причины, until в прошлом или попытка заблокировать себя"
+// @Failure      401      {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      403      {object}  dto.ErrorResponse  "Нет роли admin"
+// @Failure      404      {object}  dto.ErrorResponse  "Пользователь не найден"
+// @Failure      500      {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/block [post]
+func (h *AuthHandler) AdminBlockUser(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+	var req dto.BlockUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "reason_required"})
+		return
+	}
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_until"})
+		return
+	}
+
+	if err := h.svc.BlockUser(c.Request.Context(), currentUserID(c), userID, req.Reason, req.Until); err != nil {
+		abortAdminError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AdminUnblockUser снимает блокировку
+// @Summary      Разблокировать
+// @Description  Только для admin
+// @Tags         admin
+// @Accept       json
+// @Param        id       path      string                true   "ID пользователя"
+// @Param        request  body      dto.BlockUserRequest  false  "Причина"
+// @Success      204      {string}  string             "Блокировка снята, тело отсутствует"
+// @Failure      400      {object}  dto.ErrorResponse  "Некорректный ID"
+// @Failure      401      {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      403      {object}  dto.ErrorResponse  "Нет роли admin"
+// @Failure      404      {object}  dto.ErrorResponse  "Пользователь не найден"
+// @Failure      500      {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/unblock [post]
+func (h *AuthHandler) AdminUnblockUser(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+	// тело необязательно
+	var req dto.BlockUserRequest
+	_ = c.ShouldBindJSON(&req)
+
+	if err := h.svc.UnblockUser(c.Request.Context(), currentUserID(c), userID, req.Reason); err != nil {
+		abortAdminError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AdminUserBlocks возвращает историю блокировок
+// @Summary      История блокировок
+// @Description  Только для admin. Новые сверху.
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "ID пользователя"
+// @Success      200  {array}   dto.UserBlockResponse
+// @Failure      400  {object}  dto.ErrorResponse  "Некорректный ID"
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      403  {object}  dto.ErrorResponse  "Нет роли admin"
+// @Failure      404  {object}  dto.ErrorResponse  "Пользователь не найден"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/blocks [get]
+func (h *AuthHandler) AdminUserBlocks(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+
+	blocks, err := h.svc.UserBlocks(c.Request.Context(), userID)
+	if err != nil {
+		abortAdminError(c, err)
+		return
+	}
+
+	now := time.Now()
+	resp := make([]dto.UserBlockResponse, 0, len(blocks))
+	for _, b := range blocks {
+		resp = append(resp, dto.UserBlockResponse{
+			ID:           b.ID.String(),
+			Reason:       b.Reason,
+			ActorID:      uuidString(b.ActorID),
+			BlockedAt:    b.BlockedAt,
+			BlockedUntil: b.BlockedUntil,
+			LiftedAt:     b.LiftedAt,
+			LiftedBy:     uuidString(b.LiftedBy),
+			LiftReason:   b.LiftReason,
+			Active:       b.LiftedAt == nil && (b.BlockedUntil == nil || now.Before(*b.BlockedUntil)),
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// AdminForcePasswordReset требует от пользователя сменить пароль
+// @Summary      Принудительный сброс пароля
+// @Description  Только для admin. Вход закрывается до сброса, все сессии завершаются, пользователю уходит код сброса. Код возвращается в ответе только при svc.devMode.
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      string  true  "ID пользователя"
+// @Success      200  {object}  dto.StartResetDevResponse  "svc.devMode: код в ответе"
+// @Success      204  {string}  string             "Сброс назначен, тело отсутствует"
+// @Failure      400  {object}  dto.ErrorResponse  "Некорректный ID"
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      403  {object}  dto.ErrorResponse  "Нет роли admin"
+// @Failure      404  {object}  dto.ErrorResponse  "Пользователь не найден"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/force-password-reset [post]
+func (h *AuthHandler) AdminForcePasswordReset(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+
+	code, err := h.svc.ForcePasswordReset(c.Request.Context(), currentUserID(c), userID)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			log.Error().Err(err).Str("operation", "AuthHandler.AdminForcePasswordReset").Msg("failed to force password reset")
+		}
+		abortAdminError(c, err)
+		return
+	}
+	if code == "" {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, dto.StartResetDevResponse{DevCode: code})
+}
+
+// AdminRevokeSessions разлогинивает пользователя на всех устройствах
+// @Summary      Завершить все сессии
+// @Description  Только для admin
+// @Tags         admin
+// @Param        id   path      string  true  "ID пользователя"
+// @Success      204  {string}  string             "Сессии завершены, тело отсутствует"
+// @Failure      400  {object}  dto.ErrorResponse  "Некорректный ID"
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      403  {object}  dto.ErrorResponse  "Нет роли admin"
+// @Failure      404  {object}  dto.ErrorResponse  "Пользователь не найден"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/revoke-sessions [post]
+func (h *AuthHandler) AdminRevokeSessions(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.RevokeUserSessions(c.Request.Context(), currentUserID(c), userID); err != nil {
+		abortAdminError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func pathUserID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func abortAdminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found"})
+	case errors.Is(err, domain.ErrSelfAction):
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "self_action"})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+	}
+}
+
+func uuidString(id *uuid.UUID) *string {
+	if id == nil {
+		return nil
+	}
+	s := id.String()
+	return &s
+}
+
+func toAdminUser(u domain.User) dto.AdminUserResponse {
+	return dto.AdminUserResponse{
+		ID:                u.ID.String(),
+		Email:             u.Email,
+		EmailVerified:     u.EmailVerifiedAt != nil,
+		Blocked:           u.Blocked(time.Now()),
+		BlockedReason:     u.BlockedReason,
+		BlockedUntil:      u.BlockedUntil,
+		MustResetPassword: u.MustResetPassword,
+		CreatedAt:         u.CreatedAt,
+		LastLoginAt:       u.LastLoginAt,
+	}
+}
@@ -0,0 +1,147 @@
+package httpin
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"auth/internal/adapter/in/http/dto"
+	"auth/internal/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SecurityActivity возвращает последние события безопасности текущего пользователя
+// @Summary      Недавняя активность
+// @Description  Входы, неудачные попытки, смены пароля, выходы и действия администраторов с аккаунтом, новые сверху
+// @Tags         security
+// @Produce      json
+// @Param        limit  query     int  false  "Сколько событий (по умолчанию 20, максимум 100)"
+// @Success      200    {array}   dto.SecurityEventResponse
+// @Failure      401    {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      500    {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /security/activity [get]
+func (h *AuthHandler) SecurityActivity(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	events, err := h.svc.SecurityActivity(c.Request.Context(), currentUserID(c), limit)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		return
+	}
+
+	resp := make([]dto.SecurityEventResponse, 0, len(events))
+	for _, e := range events {
+		resp = append(resp, dto.SecurityEventResponse{
+			Type:      e.Type,
+			IP:        e.IP,
+			UserAgent: e.UserAgent,
+			CreatedAt: e.CreatedAt,
+			ByAdmin:   e.ActorID != nil,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// AdminEvents ищет по журналу событий
+// @Summary      Журнал событий
+// @Description  Только для admin. Новые сверху; следующая страница — с before_id из ответа.
+// @Tags         admin
+// @Produce      json
+// @Param        user_id    query     string  false  "ID пользователя"
+// @Param        actor_id   query     string  false  "ID администратора, выполнившего действие"
+// @Param        type       query     string  false  "Типы событий через запятую (login.failure,refresh.reuse)"
+// @Param        ip         query     string  false  "Адрес или подсеть CIDR"
+// @Param        since      query     string  false  "Начало периода, RFC 3339"
+// @Param        until      query     string  false  "Конец периода (не включительно), RFC 3339"
+// @Param        before_id  query     int     false  "Курсор"
+// @Param        limit      query     int     false  "Размер страницы (по умолчанию 50, максимум 500)"
+// @Success      200        {object}  dto.AuthEventListResponse
+// @Failure      400        {object}  dto.ErrorResponse  "Некорректный фильтр"
+// @Failure      401        {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      403        {object}  dto.ErrorResponse  "Нет роли admin"
+// @Failure      500        {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/events [get]
+func (h *AuthHandler) AdminEvents(c *gin.Context) {
+	f, err := eventFilter(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_filter"})
+		return
+	}
+
+	page, err := h.svc.SearchEvents(c.Request.Context(), f)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidFilter) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_filter"})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		return
+	}
+
+	resp := dto.AuthEventListResponse{Events: make([]dto.AuthEventResponse, 0, len(page.Events))}
+	for _, e := range page.Events {
+		details := e.Details
+		if details == nil {
+			details = map[string]any{}
+		}
+		resp.Events = append(resp.Events, dto.AuthEventResponse{
+			ID:        e.ID,
+			UserID:    uuidString(e.UserID),
+			ActorID:   uuidString(e.ActorID),
+			Type:      e.Type,
+			IP:        e.IP,
+			UserAgent: e.UserAgent,
+			Details:   details,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	if page.NextBeforeID != 0 {
+		resp.NextBeforeID = &page.NextBeforeID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func eventFilter(c *gin.Context) (domain.EventFilter, error) {
+	var f domain.EventFilter
+	for key, dst := range map[string]**uuid.UUID{"user_id": &f.UserID, "actor_id": &f.ActorID} {
+		if v := c.Query(key); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				return f, err
+			}
+			*dst = &id
+		}
+	}
+	for key, dst := range map[string]**time.Time{"since": &f.Since, "until": &f.Until} {
+		if v := c.Query(key); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, err
+			}
+			*dst = &t
+		}
+	}
+	if v := c.Query("type"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				f.Types = append(f.Types, t)
+			}
+		}
+	}
+	f.IP = strings.TrimSpace(c.Query("ip"))
+	if v := c.Query("before_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return f, err
+		}
+		f.BeforeID = id
+	}
+	f.Limit, _ = strconv.Atoi(c.Query("limit"))
+	return f, nil
+}
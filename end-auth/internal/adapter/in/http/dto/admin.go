@@ -0,0 +1,48 @@
+package dto
+
+import "time"
+
+type AdminUserResponse struct {
+	ID                string     `json:"id"`
+	Email             string     `json:"email"`
+	EmailVerified     bool       `json:"email_verified"`
+	Blocked           bool       `json:"blocked"`
+	BlockedReason     *string    `json:"blocked_reason,omitempty"`
+	BlockedUntil      *time.Time `json:"blocked_until,omitempty"`
+	MustResetPassword bool       `json:"must_reset_password"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastLoginAt       *time.Time `json:"last_login_at,omitempty"`
+}
+
+type AdminUserListResponse struct {
+	Users  []AdminUserResponse `json:"users"`
+	Total  int                 `json:"total"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+}
+
+type AdminUserDetailsResponse struct {
+	AdminUserResponse
+	Roles      []string `json:"roles"`
+	MFAEnabled bool     `json:"mfa_enabled"`
+}
+
+// BlockUserRequest — причина обязательна при блокировке и необязательна при разблокировке.
+// Until задаёт конец временной блокировки (RFC 3339); без него блокировка бессрочная.
+type BlockUserRequest struct {
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until,omitempty"`
+}
+
+type UserBlockResponse struct {
+	ID           string     `json:"id"`
+	Reason       string     `json:"reason"`
+	ActorID      *string    `json:"actor_id,omitempty"`
+	BlockedAt    time.Time  `json:"blocked_at"`
+	BlockedUntil *time.Time `json:"blocked_until,omitempty"`
+	LiftedAt     *time.Time `json:"lifted_at,omitempty"`
+	LiftedBy     *string    `json:"lifted_by,omitempty"`
+	LiftReason   *string    `json:"lift_reason,omitempty"`
+	// Active — блокировка действует сейчас.
+	Active bool `json:"active"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+import "time"
+
+// SecurityEventResponse — событие из истории активности, которую видит сам пользователь.
+type SecurityEventResponse struct {
+	Type      string    `json:"type"`
+	IP        *string   `json:"ip,omitempty"`
+	UserAgent *string   `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// ByAdmin — действие выполнил администратор.
+	ByAdmin bool `json:"by_admin"`
+}
+
+type AuthEventResponse struct {
+	ID        int64          `json:"id"`
+	UserID    *string        `json:"user_id,omitempty"`
+	ActorID   *string        `json:"actor_id,omitempty"`
+	Type      string         `json:"type"`
+	IP        *string        `json:"ip,omitempty"`
+	UserAgent *string        `json:"user_agent,omitempty"`
+	Details   map[string]any `json:"details"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type AuthEventListResponse struct {
+	Events []AuthEventResponse `json:"events"`
+	// NextBeforeID — курсор следующей страницы; нет, если страница последняя.
+	NextBeforeID *int64 `json:"next_before_id,omitempty"`
+}
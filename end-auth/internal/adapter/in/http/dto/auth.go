@@ -1,17 +1,39 @@
 package dto
 
+import (
+	"time"
+
+	"auth/internal/keys"
+)
+
 type RegisterRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name,omitempty"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name,omitempty"`
+}
+
+type MagicLinkStartRequest struct {
+	Email string `json:"email"`
+}
+
+type MagicLinkConfirmRequest struct {
+	Token      string `json:"token"`
+	DeviceName string `json:"device_name,omitempty"`
+}
+
+type MagicLinkDevResponse struct {
+	DevToken string `json:"dev_token"`
 }
 
 type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token"`
+	DeviceName   string `json:"device_name,omitempty"`
 }
 
 type TokenResponse struct {
@@ -19,6 +41,11 @@ type TokenResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
 type StartResetRequest struct {
 	Email string `json:"email"`
 }
@@ -36,14 +63,64 @@ type ConfirmResetRequest struct {
 // 	Claims    map[string]any `json:"claims"`
 // }
 
+// ValidateResponse — владелец токена. TokenType: "user" — токен пользователя,
+// "personal" — персональный токен (права ограничены scopes), "app" — токен
+// стороннего приложения client_id от имени пользователя (права ограничены scopes),
+// "service" — токен сервиса (client_credentials), у него вместо user_id — client_id.
 type ValidateResponse struct {
-	UserID string `json:"user_id"`
+	TokenType     string   `json:"token_type"`
+	UserID        string   `json:"user_id,omitempty"`
+	EmailVerified bool     `json:"email_verified"`
+	Roles         []string `json:"roles"`
+	ClientID      string   `json:"client_id,omitempty"`
+	Scopes        []string `json:"scopes,omitempty"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+type StatusResponse struct {
+	Status string `json:"status"`
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// BlockedResponse — ответ для заблокированного аккаунта; blocked_until нет у бессрочной блокировки.
+type BlockedResponse struct {
+	Error        string     `json:"error"`
+	BlockedUntil *time.Time `json:"blocked_until,omitempty"`
+}
+
+// WeakPasswordResponse — пароль не прошёл политику. violations: too_short, too_long,
+// too_simple, contains_email, breached, reused.
+type WeakPasswordResponse struct {
+	Error      string   `json:"error"`
+	Violations []string `json:"violations,omitempty"`
+}
+
 type StartResetDevResponse struct {
 	DevCode string `json:"dev_code"`
 }
+
+type JWKSResponse struct {
+	Keys []keys.JWK `json:"keys"`
+}
+
+type SessionResponse struct {
+	ID           string    `json:"id"`
+	DeviceName   *string   `json:"device_name,omitempty"`
+	UserAgent    *string   `json:"user_agent,omitempty"`
+	IP           *string   `json:"ip,omitempty"`
+	SignedInAt   time.Time `json:"signed_in_at"`
+	LastActiveAt time.Time `json:"last_active_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	// Current — сессия, которой выдан токен запроса.
+	Current bool `json:"current"`
+}
@@ -0,0 +1,77 @@
+package dto
+
+import "time"
+
+// OAuthTokenRequest — POST /oauth/token (application/x-www-form-urlencoded).
+type OAuthTokenRequest struct {
+	GrantType string `form:"grant_type"`
+	Scope     string `form:"scope"`
+	// authorization_code
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	// refresh_token
+	RefreshToken string `form:"refresh_token"`
+}
+
+// OAuthTokenResponse — ответ RFC 6749; refresh-токена нет у client_credentials.
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// AuthorizeDecisionRequest — решение пользователя на экране согласия вместе
+// с параметрами исходного запроса авторизации.
+type AuthorizeDecisionRequest struct {
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	ResponseType        string `json:"response_type"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	Approve             bool   `json:"approve"`
+}
+
+type AuthorizePromptResponse struct {
+	ClientID   string   `json:"client_id"`
+	ClientName string   `json:"client_name"`
+	FirstParty bool     `json:"first_party"`
+	Scopes     []string `json:"scopes"`
+	// ConsentRequired — false: спрашивать пользователя не нужно, можно сразу отправить approve=true.
+	ConsentRequired bool `json:"consent_required"`
+}
+
+// AuthorizeRedirectResponse — куда вернуть браузер (redirect_uri приложения с code или error).
+type AuthorizeRedirectResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// AuthorizeErrorResponse — ошибка запроса авторизации. Если redirect_to задан,
+// браузер нужно вернуть в приложение по этому адресу.
+type AuthorizeErrorResponse struct {
+	Error      string `json:"error"`
+	RedirectTo string `json:"redirect_to,omitempty"`
+}
+
+type AuthorizedAppResponse struct {
+	ClientID  string    `json:"client_id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	GrantedAt time.Time `json:"granted_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IntrospectionResponse — ответ RFC 7662; у неактивного токена есть только active=false.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Sub       string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+}
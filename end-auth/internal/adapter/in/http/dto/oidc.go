@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+type OIDCProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+// OIDCExchangeRequest — login_code, с которым браузер вернулся на фронтенд после входа у провайдера.
+type OIDCExchangeRequest struct {
+	LoginCode  string `json:"login_code"`
+	DeviceName string `json:"device_name,omitempty"`
+}
+
+type IdentityResponse struct {
+	Provider    string     `json:"provider"`
+	Email       *string    `json:"email,omitempty"`
+	LinkedAt    time.Time  `json:"linked_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+type CreatePersonalTokenRequest struct {
+	Name string `json:"name"`
+	// Scopes — права токена, например trainings:read, user-info:write.
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type PersonalTokenResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Expired    bool       `json:"expired"`
+}
+
+// CreatedPersonalTokenResponse — ответ на создание; token показывается только здесь.
+type CreatedPersonalTokenResponse struct {
+	PersonalTokenResponse
+	Token string `json:"token"`
+}
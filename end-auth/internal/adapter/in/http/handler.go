@@ -1,7 +1,10 @@
 package httpin
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"auth/internal/adapter/in/http/dto"
@@ -9,6 +12,8 @@ import (
 	"auth/internal/service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
 )
 
 type AuthHandler struct {
@@ -19,6 +24,60 @@ func NewAuthHandler(svc *service.Service) *AuthHandler {
 	return &AuthHandler{svc: svc}
 }
 
+const (
+	maxUserAgentLen  = 512
+	maxDeviceNameLen = 64
+)
+
+// clientInfo собирает метаданные устройства. IP берётся через gin.ClientIP,
+// который учитывает X-Forwarded-For/X-Real-IP только от доверенных прокси.
+func clientInfo(c *gin.Context, deviceName string) service.ClientInfo {
+	return service.ClientInfo{
+		UserAgent:  truncate(c.Request.UserAgent(), maxUserAgentLen),
+		IP:         c.ClientIP(),
+		DeviceName: truncate(strings.TrimSpace(deviceName), maxDeviceNameLen),
+	}
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// abortIfBlocked отвечает 403 со сроком окончания, если аккаунт заблокирован.
+func abortIfBlocked(c *gin.Context, err error) bool {
+	var blocked *domain.BlockedError
+	if !errors.As(err, &blocked) {
+		return false
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, dto.BlockedResponse{Error: "blocked", BlockedUntil: blocked.Until})
+	return true
+}
+
+// abortIfWeakPassword отвечает 400 со списком нарушений, если пароль не прошёл политику.
+func abortIfWeakPassword(c *gin.Context, err error) bool {
+	var weak *domain.PasswordPolicyError
+	if !errors.As(err, &weak) {
+		return false
+	}
+	c.AbortWithStatusJSON(http.StatusBadRequest, dto.WeakPasswordResponse{Error: "weak_password", Violations: weak.Violations})
+	return true
+}
+
+// abortIfLocked отвечает 429 с Retry-After, если вход временно закрыт после неудачных попыток.
+func abortIfLocked(c *gin.Context, err error) bool {
+	var locked *domain.LockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, dto.ErrorResponse{Error: "too_many_attempts"})
+	return true
+}
+
 func bearer(c *gin.Context) string {
 	h := c.GetHeader("Authorization")
 	if len(h) < 7 {
@@ -32,13 +91,15 @@ func bearer(c *gin.Context) string {
 
 // Register регистрирует нового пользователя
 // @Summary      Регистрация пользователя
-// @Description  Создаёт нового пользователя и возвращает пару access/refresh токенов
+// @Description  Создаёт нового пользователя, отправляет письмо для подтверждения email и возвращает пару access/refresh токенов.
+// @Description  Если вход без подтверждения email запрещён (svc.emailVerification.requireForLogin), токены не выдаются — ответ 202.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        request  body      dto.RegisterRequest  true  "Учётные данные пользователя"
 // @Success      201      {object}  dto.TokenResponse
-// @Failure      400      {object}  dto.ErrorResponse   "Неверный формат запроса"
+// @Success      202      {object}  dto.StatusResponse  "Пользователь создан, нужно подтвердить email"
+// @Failure      400      {object}  dto.WeakPasswordResponse  "Неверный формат запроса (bad_request) или пароль не прошёл политику (weak_password)"
 // @Failure      409      {object}  dto.ErrorResponse   "Пользователь с таким email уже существует"
 // @Failure      500      {object}  dto.ErrorResponse   "Внутренняя ошибка сервера"
 // @Router       /register [post]
@@ -49,12 +110,19 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	tp, err := h.svc.Register(c.Request.Context(), req.Email, req.Password)
+	tp, err := h.svc.Register(c.Request.Context(), req.Email, req.Password, clientInfo(c, req.DeviceName))
 	if err != nil {
+		if abortIfWeakPassword(c, err) {
+			return
+		}
 		if err == domain.ErrAlreadyExists {
 			c.AbortWithStatusJSON(http.StatusConflict, dto.ErrorResponse{Error: "email_exists"})
 			return
 		}
+		if err == domain.ErrEmailNotVerified {
+			c.JSON(http.StatusAccepted, dto.StatusResponse{Status: "email_verification_required"})
+			return
+		}
 		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
 		return
 	}
@@ -67,15 +135,20 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 // Login аутентифицирует пользователя по email и паролю
 // @Summary      Логин пользователя
-// @Description  Проверяет email/пароль и возвращает пару access/refresh токенов
+// @Description  Проверяет email/пароль и возвращает пару access/refresh токенов.
+// @Description  Неудачные попытки считаются по аккаунту и по IP; после серии неудач вход временно закрывается.
+// @Description  Если у пользователя включена 2FA, вместо токенов возвращается mfa_token (202) — вход завершается через /login/mfa.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        request  body      dto.LoginRequest  true  "Учётные данные пользователя"
 // @Success      200      {object}  dto.TokenResponse
+// @Success      202      {object}  dto.MFAChallengeResponse  "Нужен второй фактор"
 // @Failure      400      {object}  dto.ErrorResponse   "Неверный формат запроса"
 // @Failure      401      {object}  dto.ErrorResponse   "Неверные учётные данные"
-// @Failure      403      {object}  dto.ErrorResponse   "Пользователь заблокирован"
+// @Failure      403      {object}  dto.BlockedResponse "Пароль верный, но пользователь заблокирован (blocked_until — до какого времени), email не подтверждён или требуется сброс пароля"
+// @Failure      429      {object}  dto.ErrorResponse   "Слишком много неудачных попыток; ждать Retry-After секунд"
+// @Header       429      {integer} Retry-After         "Через сколько секунд можно повторить попытку"
 // @Failure      500      {object}  dto.ErrorResponse   "Внутренняя ошибка сервера"
 // @Router       /login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -85,22 +158,35 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	tp, err := h.svc.Login(c.Request.Context(), req.Email, req.Password)
+	res, err := h.svc.Login(c.Request.Context(), req.Email, req.Password, clientInfo(c, req.DeviceName))
 	if err != nil {
+		if abortIfLocked(c, err) || abortIfBlocked(c, err) {
+			return
+		}
 		switch err {
 		case domain.ErrInvalidCreds:
 			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "invalid_credentials"})
-		case domain.ErrBlockedUser:
-			c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{Error: "blocked"})
+		case domain.ErrEmailNotVerified:
+			c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{Error: "email_not_verified"})
+		case domain.ErrPasswordResetRequired:
+			c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{Error: "password_reset_required"})
 		default:
 			c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
 		}
 		return
 	}
 
+	if res.MFAChallenge != "" {
+		c.JSON(http.StatusAccepted, dto.MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    res.MFAChallenge,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, dto.TokenResponse{
-		AccessToken:  tp.AccessToken,
-		RefreshToken: tp.RefreshToken,
+		AccessToken:  res.Tokens.AccessToken,
+		RefreshToken: res.Tokens.RefreshToken,
 	})
 }
 
@@ -114,6 +200,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 // @Success      200      {object}  dto.TokenResponse
 // @Failure      400      {object}  dto.ErrorResponse   "Неверный формат запроса"
 // @Failure      401      {object}  dto.ErrorResponse   "Невалидный или просроченный refresh-токен"
+// @Failure      403      {object}  dto.BlockedResponse "Пользователь заблокирован (blocked_until — до какого времени)"
 // @Failure      500      {object}  dto.ErrorResponse   "Внутренняя ошибка сервера"
 // @Router       /refresh [post]
 func (h *AuthHandler) Refresh(c *gin.Context) {
@@ -123,8 +210,11 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
-	tp, err := h.svc.Refresh(c.Request.Context(), req.RefreshToken)
+	tp, err := h.svc.Refresh(c.Request.Context(), req.RefreshToken, clientInfo(c, req.DeviceName))
 	if err != nil {
+		if abortIfBlocked(c, err) {
+			return
+		}
 		c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "invalid_refresh"})
 		return
 	}
@@ -137,7 +227,7 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 
 // Logout инвалидирует один refresh-токен (выход с текущего устройства)
 // @Summary      Логаут с одного устройства
-// @Description  Помечает переданный refresh-токен как отозванный. Идемпотентен.
+// @Description  Помечает переданный refresh-токен как отозванный; access-токен из заголовка Authorization, если он есть, тоже отзывается. Идемпотентен.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -148,19 +238,74 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 func (h *AuthHandler) Logout(c *gin.Context) {
 	var req dto.RefreshRequest
 	_ = c.ShouldBindJSON(&req)
-	_ = h.svc.Logout(c.Request.Context(), req.RefreshToken)
+	_ = h.svc.Logout(c.Request.Context(), req.RefreshToken, bearer(c))
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutAll разлогинивает пользователя на всех устройствах
+// @Summary      Логаут со всех устройств
+// @Description  Отзывает все refresh-токены и все выданные access-токены текущего пользователя
+// @Tags         auth
+// @Produce      json
+// @Success      204  {string}  string             "Успешный логаут, тело отсутствует"
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	if err := h.svc.LogoutAll(c.Request.Context(), currentUserID(c)); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ChangePassword меняет пароль залогиненного пользователя
+// @Summary      Смена пароля
+// @Description  Проверяет текущий пароль и устанавливает новый. Остальные устройства разлогиниваются, текущая сессия сохраняется. Все access-токены, включая текущий, отзываются — обновите его через /refresh.
+// @Tags         password
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.ChangePasswordRequest  true  "Текущий и новый пароль"
+// @Success      204      {string}  string                     "Пароль изменён, тело отсутствует"
+// @Failure      400      {object}  dto.WeakPasswordResponse   "Неверный формат запроса или пароль не прошёл политику"
+// @Failure      401      {object}  dto.ErrorResponse          "Нет токена или он невалиден"
+// @Failure      403      {object}  dto.ErrorResponse          "Неверный текущий пароль"
+// @Failure      500      {object}  dto.ErrorResponse          "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /password/change [post]
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var req dto.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return
+	}
+
+	err := h.svc.ChangePassword(c.Request.Context(), currentUserID(c), req.CurrentPassword, req.NewPassword, currentSessionID(c))
+	if err != nil {
+		if abortIfWeakPassword(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, domain.ErrInvalidCreds):
+			c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{Error: "invalid_credentials"})
+		default:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		}
+		return
+	}
 	c.Status(http.StatusNoContent)
 }
 
 // StartReset начинает процесс сброса пароля (отправка OTP-кода)
 // @Summary      Начало сброса пароля
-// @Description  Генерирует одноразовый код для сброса пароля и сохраняет его. В dev-режиме возвращает код в ответе.
+// @Description  Генерирует одноразовый код для сброса пароля и отправляет его на email. Код возвращается в ответе только при svc.devMode.
 // @Tags         password-reset
 // @Accept       json
 // @Produce      json
 // @Param        request  body      dto.StartResetRequest       true  "Email пользователя"
-// @Success      200      {object}  dto.StartResetDevResponse   "Dev-режим: OTP-код в ответе"
-// @Success      204      {string}  string                      "В проде: всегда 204, даже если email не найден"
+// @Success      200      {object}  dto.StartResetDevResponse   "svc.devMode: OTP-код в ответе"
+// @Success      204      {string}  string                      "Всегда 204, даже если email не найден"
 // @Failure      400      {object}  dto.ErrorResponse           "Неверный формат запроса"
 // @Router       /password/reset/start [post]
 func (h *AuthHandler) StartReset(c *gin.Context) {
@@ -172,11 +317,14 @@ func (h *AuthHandler) StartReset(c *gin.Context) {
 
 	code, err := h.svc.StartPasswordResetOTP(c.Request.Context(), req.Email)
 	if err != nil {
+		// наружу не отдаём, чтобы по ответу нельзя было понять, есть ли такой email
+		log.Error().Err(err).Str("operation", "AuthHandler.StartReset").Msg("failed to start password reset")
+	}
+	if code == "" {
 		c.Status(http.StatusNoContent)
 		return
 	}
-	// lol, for testing purposes
-	c.JSON(http.StatusOK, gin.H{"dev_code": code})
+	c.JSON(http.StatusOK, dto.StartResetDevResponse{DevCode: code})
 }
 
 // ConfirmReset подтверждает сброс пароля по OTP-коду
@@ -187,7 +335,7 @@ func (h *AuthHandler) StartReset(c *gin.Context) {
 // @Produce      json
 // @Param        request  body      dto.ConfirmResetRequest  true  "Email, OTP-код и новый пароль"
 // @Success      204      {string}  string                   "Пароль успешно изменён, тело отсутствует"
-// @Failure      400      {object}  dto.ErrorResponse        "Неверный код или некорректные данные"
+// @Failure      400      {object}  dto.WeakPasswordResponse "Неверный код (invalid_code) или новый пароль не прошёл политику (weak_password); при weak_password попытка ввода кода не расходуется"
 // @Router       /password/reset/confirm [post]
 func (h *AuthHandler) ConfirmReset(c *gin.Context) {
 	var req dto.ConfirmResetRequest
@@ -197,15 +345,70 @@ func (h *AuthHandler) ConfirmReset(c *gin.Context) {
 	}
 
 	if err := h.svc.ConfirmPasswordResetOTP(c.Request.Context(), req.Email, req.Code, req.NewPassword); err != nil {
+		if abortIfWeakPassword(c, err) {
+			return
+		}
 		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_code"})
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
 
+// VerifyEmail подтверждает email по токену из письма
+// @Summary      Подтверждение email
+// @Description  Принимает токен из письма, отправленного при регистрации. Повторное подтверждение тем же токеном не считается ошибкой.
+// @Tags         email
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.VerifyEmailRequest  true  "Токен из письма"
+// @Success      204      {string}  string                  "Email подтверждён, тело отсутствует"
+// @Failure      400      {object}  dto.ErrorResponse       "Неверный формат запроса или невалидный/просроченный токен"
+// @Failure      500      {object}  dto.ErrorResponse       "Внутренняя ошибка сервера"
+// @Router       /email/verify [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req dto.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return
+	}
+
+	if err := h.svc.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		if errors.Is(err, domain.ErrInvalidToken) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_token"})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ResendVerification повторно отправляет письмо для подтверждения email
+// @Summary      Повторная отправка письма подтверждения
+// @Description  Отправляет новое письмо, если аккаунт существует и email ещё не подтверждён. Всегда отвечает 204, чтобы не раскрывать наличие аккаунта.
+// @Tags         email
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.ResendVerificationRequest  true  "Email пользователя"
+// @Success      204      {string}  string                         "Тело отсутствует"
+// @Failure      400      {object}  dto.ErrorResponse              "Неверный формат запроса"
+// @Router       /email/verify/resend [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req dto.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return
+	}
+
+	if err := h.svc.ResendVerification(c.Request.Context(), req.Email); err != nil {
+		log.Error().Err(err).Str("operation", "AuthHandler.ResendVerification").Msg("failed to resend email verification")
+	}
+	c.Status(http.StatusNoContent)
+}
+
 // Validate проверяет валидность access-токена
 // @Summary      Валидация access-токена
-// @Description  Проверяет access-токен, убеждается что пользователь существует и не заблокирован, и возвращает его ID.
+// @Description  Проверяет access-токен, убеждается что пользователь существует и не заблокирован, и возвращает его ID и статус подтверждения email. Токен сервиса (client_credentials) возвращается с token_type=service, client_id и scopes, персональный токен — с token_type=personal, user_id и scopes, токен стороннего приложения — с token_type=app, user_id, client_id и scopes (у обоих без ролей).
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -221,13 +424,126 @@ func (h *AuthHandler) Validate(c *gin.Context) {
 		return
 	}
 
-	userID, err := h.svc.ValidateAccess(c.Request.Context(), access)
+	if pat, err := h.svc.ValidatePersonalToken(c.Request.Context(), access); err == nil {
+		c.JSON(http.StatusOK, dto.ValidateResponse{
+			TokenType:     "personal",
+			UserID:        pat.UserID.String(),
+			EmailVerified: pat.EmailVerified,
+			Roles:         []string{},
+			Scopes:        pat.Scopes,
+		})
+		return
+	}
+
+	info, err := h.svc.ValidateAccess(c.Request.Context(), access)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "invalid_token"})
+		svcInfo, svcErr := h.svc.ValidateService(c.Request.Context(), access)
+		if svcErr != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "invalid_token"})
+			return
+		}
+		c.JSON(http.StatusOK, dto.ValidateResponse{
+			TokenType: "service",
+			Roles:     []string{},
+			ClientID:  svcInfo.ClientID,
+			Scopes:    svcInfo.Scopes,
+		})
+		return
+	}
+
+	if info.ClientID != "" {
+		c.JSON(http.StatusOK, dto.ValidateResponse{
+			TokenType:     "app",
+			UserID:        info.UserID.String(),
+			EmailVerified: info.EmailVerified,
+			Roles:         []string{},
+			ClientID:      info.ClientID,
+			Scopes:        info.Scopes,
+		})
 		return
 	}
 
 	c.JSON(http.StatusOK, dto.ValidateResponse{
-		UserID: userID.String(),
+		TokenType:     "user",
+		UserID:        info.UserID.String(),
+		EmailVerified: info.EmailVerified,
+		Roles:         nonNil(info.Roles),
 	})
 }
+
+// JWKS отдаёт публичные ключи подписи access-токенов
+// @Summary      Публичные ключи (JWKS)
+// @Description  Возвращает набор публичных ключей (RFC 7517), которыми сервисы могут проверять access-токены локально. Ключ выбирается по заголовку kid токена.
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  dto.JWKSResponse
+// @Router       /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, dto.JWKSResponse{Keys: h.svc.JWKS()})
+}
+
+// ListSessions возвращает устройства, на которых залогинен пользователь
+// @Summary      Активные сессии
+// @Description  Возвращает список устройств (активных refresh-сессий) текущего пользователя
+// @Tags         sessions
+// @Produce      json
+// @Success      200  {array}   dto.SessionResponse
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /sessions [get]
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	sessions, err := h.svc.ListSessions(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		return
+	}
+
+	current := currentSessionID(c)
+	resp := make([]dto.SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		resp = append(resp, dto.SessionResponse{
+			ID:           s.ID.String(),
+			DeviceName:   s.DeviceName,
+			UserAgent:    s.UserAgent,
+			IP:           s.IP,
+			SignedInAt:   s.SignedInAt,
+			LastActiveAt: s.LastActiveAt,
+			ExpiresAt:    s.ExpiresAt,
+			Current:      s.ID == current,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RevokeSession разлогинивает одно устройство
+// @Summary      Завершить сессию
+// @Description  Отзывает refresh-токены выбранного устройства текущего пользователя; его access-токены перестают приниматься через /validate
+// @Tags         sessions
+// @Produce      json
+// @Param        id   path      string  true  "ID сессии"
+// @Success      204  {string}  string             "Сессия завершена, тело отсутствует"
+// @Failure      400  {object}  dto.ErrorResponse  "Некорректный ID"
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      404  {object}  dto.ErrorResponse  "Сессия не найдена"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return
+	}
+
+	if err := h.svc.RevokeSession(c.Request.Context(), currentUserID(c), id); err != nil {
+		if err == domain.ErrNotFound {
+			c.AbortWithStatusJSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found"})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,96 @@
+package httpin
+
+import (
+	"errors"
+	"net/http"
+
+	"auth/internal/adapter/in/http/dto"
+	"auth/internal/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// StartMagicLink отправляет письмо со ссылкой для входа без пароля
+// @Summary      Вход по ссылке: отправка письма
+// @Description  Отправляет на email одноразовую ссылку для входа (svc.magicLink). Токен возвращается в ответе только при svc.devMode.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.MagicLinkStartRequest  true  "Email пользователя"
+// @Success      200      {object}  dto.MagicLinkDevResponse   "svc.devMode: токен в ответе"
+// @Success      204      {string}  string                     "Всегда 204, даже если email не найден"
+// @Failure      400      {object}  dto.ErrorResponse          "Неверный формат запроса"
+// @Router       /login/magic/start [post]
+func (h *AuthHandler) StartMagicLink(c *gin.Context) {
+	var req dto.MagicLinkStartRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Email == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return
+	}
+
+	token, err := h.svc.StartMagicLink(c.Request.Context(), req.Email)
+	if err != nil {
+		// наружу не отдаём, чтобы по ответу нельзя было понять, есть ли такой email
+		log.Error().Err(err).Str("operation", "AuthHandler.StartMagicLink").Msg("failed to send magic link")
+	}
+	if token == "" {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, dto.MagicLinkDevResponse{DevToken: token})
+}
+
+// ConfirmMagicLink входит по токену из письма
+// @Summary      Вход по ссылке: подтверждение
+// @Description  Меняет токен из ссылки на пару access/refresh токенов. Ссылка одноразовая; переход по ней подтверждает email.
+// @Description  Неудачные попытки считаются так же, как в /login. Если у пользователя включена 2FA, возвращается mfa_token (202).
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.MagicLinkConfirmRequest  true  "Токен из письма"
+// @Success      200      {object}  dto.TokenResponse
+// @Success      202      {object}  dto.MFAChallengeResponse  "Нужен второй фактор"
+// @Failure      400      {object}  dto.ErrorResponse   "Неверный формат запроса"
+// @Failure      401      {object}  dto.ErrorResponse   "Ссылка недействительна, истекла или уже использована"
+// @Failure      403      {object}  dto.BlockedResponse "Пользователь заблокирован или требуется сброс пароля"
+// @Failure      429      {object}  dto.ErrorResponse   "Слишком много неудачных попыток; ждать Retry-After секунд"
+// @Header       429      {integer} Retry-After         "Через сколько секунд можно повторить попытку"
+// @Failure      500      {object}  dto.ErrorResponse   "Внутренняя ошибка сервера"
+// @Router       /login/magic/confirm [post]
+func (h *AuthHandler) ConfirmMagicLink(c *gin.Context) {
+	var req dto.MagicLinkConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return
+	}
+
+	res, err := h.svc.ConfirmMagicLink(c.Request.Context(), req.Token, clientInfo(c, req.DeviceName))
+	if err != nil {
+		if abortIfLocked(c, err) || abortIfBlocked(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, domain.ErrInvalidToken):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "invalid_token"})
+		case errors.Is(err, domain.ErrPasswordResetRequired):
+			c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{Error: "password_reset_required"})
+		default:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		}
+		return
+	}
+
+	if res.MFAChallenge != "" {
+		c.JSON(http.StatusAccepted, dto.MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    res.MFAChallenge,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.TokenResponse{
+		AccessToken:  res.Tokens.AccessToken,
+		RefreshToken: res.Tokens.RefreshToken,
+	})
+}
@@ -0,0 +1,208 @@
+package httpin
+
+import (
+	"errors"
+	"net/http"
+
+	"auth/internal/adapter/in/http/dto"
+	"auth/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoginMFA завершает вход вторым фактором
+// @Summary      Вход: второй фактор
+// @Description  Принимает mfa_token из ответа /login и код из приложения-аутентификатора (или код восстановления). Неверные коды учитываются так же, как неверные пароли.
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.MFALoginRequest  true  "Токен челленджа и код"
+// @Success      200      {object}  dto.TokenResponse
+// @Failure      400      {object}  dto.ErrorResponse  "Неверный формат запроса"
+// @Failure      401      {object}  dto.ErrorResponse  "Невалидный/просроченный mfa_token или неверный код"
+// @Failure      403      {object}  dto.BlockedResponse  "Пользователь заблокирован"
+// @Failure      429      {object}  dto.ErrorResponse  "Слишком много неудачных попыток; ждать Retry-After секунд"
+// @Failure      500      {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Router       /login/mfa [post]
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var req dto.MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.MFAToken == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return
+	}
+
+	tp, err := h.svc.CompleteMFALogin(c.Request.Context(), req.MFAToken, req.Code, clientInfo(c, req.DeviceName))
+	if err != nil {
+		if abortIfLocked(c, err) || abortIfBlocked(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, domain.ErrInvalidToken):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "invalid_mfa_token"})
+		case errors.Is(err, domain.ErrInvalidMFACode):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "invalid_mfa_code"})
+		default:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.TokenResponse{
+		AccessToken:  tp.AccessToken,
+		RefreshToken: tp.RefreshToken,
+	})
+}
+
+// MFAStatus показывает состояние 2FA текущего пользователя
+// @Summary      Состояние 2FA
+// @Tags         mfa
+// @Produce      json
+// @Success      200  {object}  dto.MFAStatusResponse
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /mfa [get]
+func (h *AuthHandler) MFAStatus(c *gin.Context) {
+	st, err := h.svc.MFAStatus(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		return
+	}
+	c.JSON(http.StatusOK, dto.MFAStatusResponse{
+		Enabled:           st.Enabled,
+		RecoveryCodesLeft: st.RecoveryCodesLeft,
+	})
+}
+
+// EnrollTOTP начинает подключение приложения-аутентификатора
+// @Summary      Подключение TOTP
+// @Description  Возвращает секрет и otpauth:// URI для QR-кода. 2FA включится после подтверждения первым кодом (/mfa/totp/confirm).
+// @Tags         mfa
+// @Produce      json
+// @Success      200  {object}  dto.TOTPEnrollResponse
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      409  {object}  dto.ErrorResponse  "2FA уже включена"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /mfa/totp/enroll [post]
+func (h *AuthHandler) EnrollTOTP(c *gin.Context) {
+	e, err := h.svc.EnrollTOTP(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		h.abortMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.TOTPEnrollResponse{
+		Secret:     e.Secret,
+		OTPAuthURI: e.URI,
+	})
+}
+
+// ConfirmTOTP включает 2FA по первому коду из приложения
+// @Summary      Подтверждение TOTP
+// @Description  Проверяет первый код из приложения, включает 2FA и возвращает коды восстановления. Коды показываются только один раз.
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.TOTPConfirmRequest     true  "Код из приложения"
+// @Success      200      {object}  dto.RecoveryCodesResponse
+// @Failure      400      {object}  dto.ErrorResponse          "Неверный формат запроса или неверный код"
+// @Failure      401      {object}  dto.ErrorResponse          "Нет токена или он невалиден"
+// @Failure      409      {object}  dto.ErrorResponse          "2FA уже включена или подключение не начато"
+// @Failure      500      {object}  dto.ErrorResponse          "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /mfa/totp/confirm [post]
+func (h *AuthHandler) ConfirmTOTP(c *gin.Context) {
+	var req dto.TOTPConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return
+	}
+
+	codes, err := h.svc.ConfirmTOTP(c.Request.Context(), currentUserID(c), req.Code)
+	if err != nil {
+		h.abortMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableTOTP выключает 2FA
+// @Summary      Отключение 2FA
+// @Description  Требует повторной аутентификации: текущий пароль и код из приложения (или код восстановления).
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.MFAReauthRequest  true  "Пароль и код"
+// @Success      204      {string}  string                "2FA выключена, тело отсутствует"
+// @Failure      400      {object}  dto.ErrorResponse     "Неверный формат запроса или неверный код"
+// @Failure      401      {object}  dto.ErrorResponse     "Нет токена или он невалиден"
+// @Failure      403      {object}  dto.ErrorResponse     "Неверный пароль"
+// @Failure      409      {object}  dto.ErrorResponse     "2FA не включена"
+// @Failure      429      {object}  dto.ErrorResponse     "Слишком много неверных паролей или кодов; ждать Retry-After секунд"
+// @Header       429      {integer} Retry-After           "Через сколько секунд можно повторить попытку"
+// @Failure      500      {object}  dto.ErrorResponse     "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /mfa/totp/disable [post]
+func (h *AuthHandler) DisableTOTP(c *gin.Context) {
+	var req dto.MFAReauthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return
+	}
+
+	if err := h.svc.DisableTOTP(c.Request.Context(), currentUserID(c), req.Password, req.Code); err != nil {
+		h.abortMFAError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes выдаёт новые коды восстановления
+// @Summary      Новые коды восстановления
+// @Description  Старые коды перестают действовать. Требует текущий пароль и код из приложения (или код восстановления).
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.MFAReauthRequest       true  "Пароль и код"
+// @Success      200      {object}  dto.RecoveryCodesResponse
+// @Failure      400      {object}  dto.ErrorResponse          "Неверный формат запроса или неверный код"
+// @Failure      401      {object}  dto.ErrorResponse          "Нет токена или он невалиден"
+// @Failure      403      {object}  dto.ErrorResponse          "Неверный пароль"
+// @Failure      409      {object}  dto.ErrorResponse          "2FA не включена"
+// @Failure      429      {object}  dto.ErrorResponse          "Слишком много неверных паролей или кодов; ждать Retry-After секунд"
+// @Header       429      {integer} Retry-After                "Через сколько секунд можно повторить попытку"
+// @Failure      500      {object}  dto.ErrorResponse          "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /mfa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req dto.MFAReauthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return
+	}
+
+	codes, err := h.svc.RegenerateRecoveryCodes(c.Request.Context(), currentUserID(c), req.Password, req.Code)
+	if err != nil {
+		h.abortMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (h *AuthHandler) abortMFAError(c *gin.Context, err error) {
+	if abortIfLocked(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, domain.ErrInvalidCreds):
+		c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{Error: "invalid_credentials"})
+	case errors.Is(err, domain.ErrInvalidMFACode):
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_mfa_code"})
+	case errors.Is(err, domain.ErrMFAEnabled):
+		c.AbortWithStatusJSON(http.StatusConflict, dto.ErrorResponse{Error: "mfa_already_enabled"})
+	case errors.Is(err, domain.ErrMFANotEnabled):
+		c.AbortWithStatusJSON(http.StatusConflict, dto.ErrorResponse{Error: "mfa_not_enabled"})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+	}
+}
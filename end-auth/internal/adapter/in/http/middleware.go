@@ -1 +1,79 @@
 package httpin
+
+import (
+	"net/http"
+	"slices"
+
+	"auth/internal/adapter/in/http/dto"
+	"auth/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	ctxUserID    = "userID"
+	ctxSessionID = "sessionID"
+	ctxRoles     = "roles"
+)
+
+// requestClient кладёт IP и user agent запроса в контекст, чтобы они попали в журнал событий.
+func requestClient(c *gin.Context) {
+	ctx := service.WithClient(c.Request.Context(), clientInfo(c, ""))
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+// RequireAuth проверяет access-токен и кладёт ID пользователя в контекст.
+// Токены сторонних приложений сюда не допускаются: управлять аккаунтом может только сам пользователь.
+func (h *AuthHandler) RequireAuth(c *gin.Context) {
+	access := bearer(c)
+	if access == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "no_bearer"})
+		return
+	}
+
+	info, err := h.svc.ValidateAccess(c.Request.Context(), access)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "invalid_token"})
+		return
+	}
+	if info.ClientID != "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{Error: "user_token_required"})
+		return
+	}
+
+	c.Set(ctxUserID, info.UserID)
+	c.Set(ctxSessionID, info.SessionID)
+	c.Set(ctxRoles, info.Roles)
+	c.Next()
+}
+
+// RequireRoles пропускает пользователя, у которого есть хотя бы одна из ролей.
+// Ставится после RequireAuth.
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, _ := c.Get(ctxRoles)
+		have, _ := v.([]string)
+		for _, r := range have {
+			if slices.Contains(roles, r) {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{Error: "forbidden"})
+	}
+}
+
+// currentUserID достаёт пользователя, которого положил RequireAuth.
+func currentUserID(c *gin.Context) uuid.UUID {
+	id, _ := c.Get(ctxUserID)
+	userID, _ := id.(uuid.UUID)
+	return userID
+}
+
+func currentSessionID(c *gin.Context) uuid.UUID {
+	id, _ := c.Get(ctxSessionID)
+	sessionID, _ := id.(uuid.UUID)
+	return sessionID
+}
@@ -0,0 +1,200 @@
+package httpin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"auth/internal/adapter/in/http/dto"
+	"auth/internal/domain"
+	"auth/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ctxClient = "oauthClient"
+
+// RequireClient аутентифицирует клиента OAuth: HTTP Basic (client_secret_basic)
+// или client_id/client_secret в теле формы (client_secret_post).
+func (h *AuthHandler) RequireClient(c *gin.Context) {
+	id, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		id, secret = c.PostForm("client_id"), c.PostForm("client_secret")
+	}
+
+	client, err := h.svc.AuthenticateClient(c.Request.Context(), id, secret)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidClient) {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "invalid_client"})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error"})
+		return
+	}
+
+	c.Set(ctxClient, client)
+	c.Next()
+}
+
+func currentClient(c *gin.Context) domain.OAuthClient {
+	v, _ := c.Get(ctxClient)
+	client, _ := v.(domain.OAuthClient)
+	return client
+}
+
+// Token выдаёт токены (RFC 6749)
+// @Summary      Токен
+// @Description  client_credentials — токен сервиса для внутренних сервисов и утилит: typ=service, принимается только на внутренних маршрутах; scope — права через пробел, без него выдаются все права клиента. authorization_code — обмен кода авторизации с code_verifier (PKCE) на токены приложения с правами, на которые согласился пользователь. refresh_token — ротация refresh-токена приложения. Публичные клиенты передают только client_id.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type     formData  string  true   "client_credentials, authorization_code или refresh_token"
+// @Param        scope          formData  string  false  "Права через пробел (client_credentials)"
+// @Param        code           formData  string  false  "Код авторизации (authorization_code)"
+// @Param        redirect_uri   formData  string  false  "Тот же redirect_uri, что в запросе авторизации"
+// @Param        code_verifier  formData  string  false  "PKCE verifier (authorization_code)"
+// @Param        refresh_token  formData  string  false  "Refresh-токен (refresh_token)"
+// @Success      200  {object}  dto.OAuthTokenResponse
+// @Failure      400  {object}  dto.ErrorResponse  "unsupported_grant_type, invalid_grant, invalid_scope или unauthorized_client"
+// @Failure      401  {object}  dto.ErrorResponse  "Неверные учётные данные клиента"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     ClientBasic
+// @Router       /oauth/token [post]
+func (h *AuthHandler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	var req dto.OAuthTokenRequest
+	if err := c.ShouldBind(&req); err != nil || req.GrantType == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_request"})
+		return
+	}
+	ctx, client := c.Request.Context(), currentClient(c)
+
+	switch req.GrantType {
+	case "client_credentials":
+		t, err := h.svc.IssueClientToken(ctx, client, req.Scope)
+		if err != nil {
+			oauthTokenError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, dto.OAuthTokenResponse{
+			AccessToken: t.AccessToken,
+			TokenType:   "Bearer",
+			ExpiresIn:   int64(t.ExpiresIn.Seconds()),
+			Scope:       strings.Join(t.Scopes, " "),
+		})
+	case "authorization_code":
+		t, err := h.svc.ExchangeAuthorizationCode(ctx, client, req.Code, req.RedirectURI, req.CodeVerifier, clientInfo(c, ""))
+		if err != nil {
+			oauthTokenError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, toAppTokens(t))
+	case "refresh_token":
+		t, err := h.svc.RefreshAppToken(ctx, client, req.RefreshToken, clientInfo(c, ""))
+		if err != nil {
+			oauthTokenError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, toAppTokens(t))
+	default:
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "unsupported_grant_type"})
+	}
+}
+
+// oauthTokenError отвечает кодом ошибки из RFC 6749, 5.2.
+func oauthTokenError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidScope):
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_scope"})
+	case errors.Is(err, domain.ErrUnauthorizedClient):
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "unauthorized_client"})
+	case errors.Is(err, domain.ErrInvalidGrant), errors.Is(err, domain.ErrInvalidRefresh),
+		errors.Is(err, domain.ErrRefreshReuse), errors.Is(err, domain.ErrBlockedUser):
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_grant"})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error"})
+	}
+}
+
+func toAppTokens(t service.AppTokens) dto.OAuthTokenResponse {
+	return dto.OAuthTokenResponse{
+		AccessToken:  t.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(t.ExpiresIn.Seconds()),
+		RefreshToken: t.RefreshToken,
+		Scope:        strings.Join(t.Scopes, " "),
+	}
+}
+
+// Introspect сообщает, действует ли токен (RFC 7662)
+// @Summary      Интроспекция токена
+// @Description  Для access- и refresh-токенов. Неизвестный, просроченный или отозванный токен — active=false. Клиент аутентифицируется через HTTP Basic или client_id/client_secret в форме; публичным клиентам интроспекция недоступна.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Токен"
+// @Param        token_type_hint  formData  string  false  "access_token или refresh_token (не обязателен)"
+// @Success      200  {object}  dto.IntrospectionResponse
+// @Failure      400  {object}  dto.ErrorResponse  "Нет токена"
+// @Failure      401  {object}  dto.ErrorResponse  "Неверные учётные данные клиента"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     ClientBasic
+// @Router       /oauth/introspect [post]
+func (h *AuthHandler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	if currentClient(c).Public() {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "invalid_client"})
+		return
+	}
+	token := c.PostForm("token")
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_request"})
+		return
+	}
+
+	in, err := h.svc.Introspect(c.Request.Context(), token)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error"})
+		return
+	}
+	if !in.Active {
+		c.JSON(http.StatusOK, dto.IntrospectionResponse{Active: false})
+		return
+	}
+	c.JSON(http.StatusOK, dto.IntrospectionResponse{
+		Active:    true,
+		Sub:       in.Subject,
+		TokenType: in.TokenType,
+		Scope:     in.Scope,
+		ClientID:  in.ClientID,
+		Iat:       in.IssuedAt.Unix(),
+		Exp:       in.ExpiresAt.Unix(),
+	})
+}
+
+// RevokeToken отзывает токен (RFC 7009)
+// @Summary      Отзыв токена
+// @Description  Отзыв refresh-токена завершает сессию устройства вместе с её access-токенами. Клиент может отозвать только выданные ему токены; любые — только наш (first-party) клиент с секретом. Неизвестный или уже недействительный токен — тоже 200.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Param        token            formData  string  true   "Токен"
+// @Param        token_type_hint  formData  string  false  "access_token или refresh_token (не обязателен)"
+// @Success      200
+// @Failure      400  {object}  dto.ErrorResponse  "Нет токена"
+// @Failure      401  {object}  dto.ErrorResponse  "Неверные учётные данные клиента"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     ClientBasic
+// @Router       /oauth/revoke [post]
+func (h *AuthHandler) RevokeToken(c *gin.Context) {
+	token := c.PostForm("token")
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_request"})
+		return
+	}
+	if err := h.svc.RevokeToken(c.Request.Context(), currentClient(c), token); err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "server_error"})
+		return
+	}
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,180 @@
+package httpin
+
+import (
+	"errors"
+	"net/http"
+
+	"auth/internal/adapter/in/http/dto"
+	"auth/internal/domain"
+	"auth/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthorizeRedirect — точка входа авторизации стороннего приложения
+// @Summary      Авторизация приложения (браузер)
+// @Description  Стандартная точка входа authorization code + PKCE (RFC 6749, RFC 7636). Проверяет client_id и redirect_uri и перенаправляет браузер на экран согласия фронтенда с теми же параметрами. Остальные параметры проверяются на экране согласия.
+// @Tags         oauth
+// @Param        client_id              query  string  true   "client_id"
+// @Param        redirect_uri           query  string  true   "Зарегистрированный redirect_uri"
+// @Param        response_type          query  string  true   "code"
+// @Param        scope                  query  string  false  "Права через пробел; без него — все права клиента"
+// @Param        state                  query  string  false  "Вернётся приложению без изменений"
+// @Param        code_challenge         query  string  true   "PKCE challenge"
+// @Param        code_challenge_method  query  string  true   "S256"
+// @Success      302
+// @Failure      400  {object}  dto.ErrorResponse  "invalid_client или invalid_redirect_uri"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Router       /oauth/authorize [get]
+func (h *AuthHandler) AuthorizeRedirect(c *gin.Context) {
+	to, err := h.svc.ConsentRedirect(c.Request.Context(), c.Query("client_id"), c.Query("redirect_uri"), c.Request.URL.RawQuery)
+	if err != nil {
+		authorizeError(c, err)
+		return
+	}
+	c.Redirect(http.StatusFound, to)
+}
+
+// AuthorizePrompt проверяет запрос авторизации для экрана согласия
+// @Summary      Экран согласия
+// @Description  Фронтенд передаёт параметры запроса авторизации как есть. consent_required=false — приложение наше или пользователь уже согласился на эти права, можно сразу подтверждать. Ошибка с redirect_to — браузер нужно вернуть в приложение по этому адресу.
+// @Tags         oauth
+// @Produce      json
+// @Param        client_id              query  string  true   "client_id"
+// @Param        redirect_uri           query  string  true   "redirect_uri"
+// @Param        response_type          query  string  true   "code"
+// @Param        scope                  query  string  false  "Права через пробел"
+// @Param        state                  query  string  false  "state"
+// @Param        code_challenge         query  string  true   "PKCE challenge"
+// @Param        code_challenge_method  query  string  true   "S256"
+// @Success      200  {object}  dto.AuthorizePromptResponse
+// @Failure      400  {object}  dto.AuthorizeErrorResponse  "invalid_client, invalid_redirect_uri, invalid_request, invalid_scope или unsupported_response_type"
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /oauth/authorize [get]
+func (h *AuthHandler) AuthorizePrompt(c *gin.Context) {
+	p, err := h.svc.PrepareAuthorization(c.Request.Context(), currentUserID(c), service.AuthorizeRequest{
+		ClientID:            c.Query("client_id"),
+		RedirectURI:         c.Query("redirect_uri"),
+		ResponseType:        c.Query("response_type"),
+		Scope:               c.Query("scope"),
+		State:               c.Query("state"),
+		CodeChallenge:       c.Query("code_challenge"),
+		CodeChallengeMethod: c.Query("code_challenge_method"),
+	})
+	if err != nil {
+		authorizeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.AuthorizePromptResponse{
+		ClientID:        p.Client.ID,
+		ClientName:      p.Client.Name,
+		FirstParty:      p.Client.FirstParty,
+		Scopes:          p.Scopes,
+		ConsentRequired: p.ConsentRequired,
+	})
+}
+
+// AuthorizeDecision принимает решение пользователя
+// @Summary      Согласие на доступ приложения
+// @Description  approve=true выдаёт приложению код авторизации, false — отказ (error=access_denied). В ответе — адрес приложения, куда нужно вернуть браузер.
+// @Tags         oauth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.AuthorizeDecisionRequest  true  "Параметры запроса авторизации и решение"
+// @Success      200      {object}  dto.AuthorizeRedirectResponse
+// @Failure      400      {object}  dto.AuthorizeErrorResponse  "Некорректный запрос авторизации"
+// @Failure      401      {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      500      {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /oauth/authorize [post]
+func (h *AuthHandler) AuthorizeDecision(c *gin.Context) {
+	var req dto.AuthorizeDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return
+	}
+
+	to, err := h.svc.Authorize(c.Request.Context(), currentUserID(c), service.AuthorizeRequest{
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		ResponseType:        req.ResponseType,
+		Scope:               req.Scope,
+		State:               req.State,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	}, req.Approve)
+	if err != nil {
+		authorizeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.AuthorizeRedirectResponse{RedirectTo: to})
+}
+
+// ListAuthorizedApps возвращает приложения, которым пользователь выдал доступ
+// @Summary      Подключённые приложения
+// @Tags         oauth
+// @Produce      json
+// @Success      200  {array}   dto.AuthorizedAppResponse
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /oauth/apps [get]
+func (h *AuthHandler) ListAuthorizedApps(c *gin.Context) {
+	apps, err := h.svc.ListAuthorizedApps(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		return
+	}
+
+	resp := make([]dto.AuthorizedAppResponse, 0, len(apps))
+	for _, a := range apps {
+		resp = append(resp, dto.AuthorizedAppResponse{
+			ClientID:  a.ClientID,
+			Name:      a.ClientName,
+			Scopes:    nonNil(a.Scopes),
+			GrantedAt: a.CreatedAt,
+			UpdatedAt: a.UpdatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RevokeAuthorizedApp отзывает доступ приложения
+// @Summary      Отключить приложение
+// @Description  Удаляет согласие и завершает все сессии приложения; его токены перестают приниматься.
+// @Tags         oauth
+// @Param        client_id  path      string  true  "client_id приложения"
+// @Success      204        {string}  string  "Доступ отозван, тело отсутствует"
+// @Failure      401        {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      404        {object}  dto.ErrorResponse  "Приложение не подключено"
+// @Failure      500        {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /oauth/apps/{client_id} [delete]
+func (h *AuthHandler) RevokeAuthorizedApp(c *gin.Context) {
+	if err := h.svc.RevokeAuthorizedApp(c.Request.Context(), currentUserID(c), c.Param("client_id")); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found"})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// authorizeError: до проверки redirect_uri возвращаем браузер некуда, после — отдаём redirect_to.
+func authorizeError(c *gin.Context, err error) {
+	var ae *service.AuthorizeError
+	switch {
+	case errors.As(err, &ae):
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.AuthorizeErrorResponse{Error: ae.Code, RedirectTo: ae.RedirectTo})
+	case errors.Is(err, domain.ErrInvalidClient):
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.AuthorizeErrorResponse{Error: "invalid_client"})
+	case errors.Is(err, domain.ErrInvalidRedirect):
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.AuthorizeErrorResponse{Error: "invalid_redirect_uri"})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+	}
+}
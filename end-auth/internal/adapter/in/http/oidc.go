@@ -0,0 +1,173 @@
+package httpin
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"auth/internal/adapter/in/http/dto"
+	"auth/internal/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// OIDCProviders возвращает список доступных провайдеров входа
+// @Summary      Провайдеры входа
+// @Tags         oidc
+// @Produce      json
+// @Success      200  {object}  dto.OIDCProvidersResponse
+// @Router       /oidc/providers [get]
+func (h *AuthHandler) OIDCProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, dto.OIDCProvidersResponse{Providers: h.svc.OIDCProviders()})
+}
+
+// StartOIDC отправляет браузер на страницу входа провайдера
+// @Summary      Вход через провайдера
+// @Description  Перенаправляет на провайдера (authorization code + PKCE) и ставит cookie oidc_state (HttpOnly, SameSite=Lax) на время входа: callback без неё не принимается. После входа браузер вернётся на return_to с ?login_code=… или ?error=…
+// @Tags         oidc
+// @Param        provider   path   string  true   "Имя провайдера"
+// @Param        return_to  query  string  false  "Куда вернуть браузер (схема, хост и порт из списка разрешённых, путь внутри разрешённого)"
+// @Success      302
+// @Failure      400  {object}  dto.ErrorResponse  "return_to не разрешён"
+// @Failure      404  {object}  dto.ErrorResponse  "Неизвестный провайдер"
+// @Failure      502  {object}  dto.ErrorResponse  "Провайдер недоступен"
+// @Router       /oidc/{provider}/start [get]
+func (h *AuthHandler) StartOIDC(c *gin.Context) {
+	start, err := h.svc.StartOIDC(c.Request.Context(), c.Param("provider"), c.Query("return_to"))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrUnknownProvider):
+			c.AbortWithStatusJSON(http.StatusNotFound, dto.ErrorResponse{Error: "unknown_provider"})
+		case errors.Is(err, domain.ErrInvalidRedirect):
+			c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_return_to"})
+		default:
+			log.Error().Err(err).Str("provider", c.Param("provider")).Msg("oidc start failed")
+			c.AbortWithStatusJSON(http.StatusBadGateway, dto.ErrorResponse{Error: "provider_unavailable"})
+		}
+		return
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    start.StateHash,
+		Path:     start.CookiePath,
+		Expires:  start.ExpiresAt,
+		MaxAge:   int(time.Until(start.ExpiresAt).Seconds()),
+		Secure:   start.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	c.Redirect(http.StatusFound, start.RedirectURL)
+}
+
+// oidcStateCookie хранит хэш state между /start и /callback.
+const oidcStateCookie = "oidc_state"
+
+// OIDCCallback принимает браузер, вернувшийся от провайдера
+// @Summary      Возврат от провайдера
+// @Description  redirect_uri для провайдера. Принимается только в браузере, начавшем вход (cookie oidc_state). Всегда перенаправляет на фронтенд: с ?login_code=… при успехе или ?error=… (invalid_state, access_denied, provider_error, email_not_verified, account_exists, blocked, internal)
+// @Tags         oidc
+// @Param        provider  path   string  true   "Имя провайдера"
+// @Param        state     query  string  true   "state из /start"
+// @Param        code      query  string  false  "Код авторизации"
+// @Param        error     query  string  false  "Ошибка от провайдера"
+// @Success      302
+// @Router       /oidc/{provider}/callback [get]
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	stateHash, _ := c.Cookie(oidcStateCookie)
+	redirect, err := h.svc.OIDCCallback(c.Request.Context(), c.Param("provider"), c.Query("state"), stateHash, c.Query("code"), c.Query("error"))
+	if stateHash != "" {
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     oidcStateCookie,
+			Path:     c.Request.URL.EscapedPath(),
+			MaxAge:   -1,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("provider", c.Param("provider")).Msg("oidc callback failed")
+	}
+	c.Redirect(http.StatusFound, redirect)
+}
+
+// OIDCExchange меняет login_code на токены
+// @Summary      Вход через провайдера: получение токенов
+// @Description  Принимает одноразовый login_code. Если у пользователя включена 2FA, отвечает 202 с mfa_token, как /login.
+// @Tags         oidc
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.OIDCExchangeRequest  true  "login_code"
+// @Success      200      {object}  dto.TokenResponse
+// @Success      202      {object}  dto.MFAChallengeResponse  "Нужен второй фактор: POST /login/mfa"
+// @Failure      400      {object}  dto.ErrorResponse  "Неверный формат запроса"
+// @Failure      401      {object}  dto.ErrorResponse  "Невалидный или просроченный login_code"
+// @Failure      403      {object}  dto.BlockedResponse  "Пользователь заблокирован, email не подтверждён или требуется сброс пароля"
+// @Failure      500      {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Router       /oidc/exchange [post]
+func (h *AuthHandler) OIDCExchange(c *gin.Context) {
+	var req dto.OIDCExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.LoginCode == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return
+	}
+
+	res, err := h.svc.ExchangeOIDCLogin(c.Request.Context(), req.LoginCode, clientInfo(c, req.DeviceName))
+	if err != nil {
+		if abortIfBlocked(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, domain.ErrInvalidToken):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "invalid_login_code"})
+		case errors.Is(err, domain.ErrEmailNotVerified):
+			c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{Error: "email_not_verified"})
+		case errors.Is(err, domain.ErrPasswordResetRequired):
+			c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{Error: "password_reset_required"})
+		default:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		}
+		return
+	}
+
+	if res.MFAChallenge != "" {
+		c.JSON(http.StatusAccepted, dto.MFAChallengeResponse{
+			MFARequired: true,
+			MFAToken:    res.MFAChallenge,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.TokenResponse{
+		AccessToken:  res.Tokens.AccessToken,
+		RefreshToken: res.Tokens.RefreshToken,
+	})
+}
+
+// ListIdentities возвращает привязанные внешние аккаунты
+// @Summary      Привязанные аккаунты
+// @Tags         oidc
+// @Produce      json
+// @Success      200  {array}   dto.IdentityResponse
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /identities [get]
+func (h *AuthHandler) ListIdentities(c *gin.Context) {
+	ids, err := h.svc.ListIdentities(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		return
+	}
+
+	resp := make([]dto.IdentityResponse, 0, len(ids))
+	for _, id := range ids {
+		resp = append(resp, dto.IdentityResponse{
+			Provider:    id.Provider,
+			Email:       id.Email,
+			LinkedAt:    id.CreatedAt,
+			LastLoginAt: id.LastLoginAt,
+		})
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,118 @@
+package httpin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"auth/internal/adapter/in/http/dto"
+	"auth/internal/domain"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreatePersonalToken выпускает персональный токен доступа
+// @Summary      Создать персональный токен
+// @Description  Токен для скриптов и интеграций с правами из scopes (trainings:read, trainings:write, user-info:read, user-info:write) и сроком действия. Показывается один раз. Передаётся как Bearer; ролей пользователя не даёт. Перестаёт действовать после смены или сброса пароля и выхода со всех устройств.
+// @Tags         personal-tokens
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.CreatePersonalTokenRequest  true  "Название, права и срок действия"
+// @Success      201      {object}  dto.CreatedPersonalTokenResponse
+// @Failure      400      {object}  dto.ErrorResponse  "Нет названия, неизвестные права (invalid_scope) или недопустимый срок (invalid_expiry)"
+// @Failure      401      {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      500      {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /personal-tokens [post]
+func (h *AuthHandler) CreatePersonalToken(c *gin.Context) {
+	var req dto.CreatePersonalTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Name) == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return
+	}
+
+	t, raw, err := h.svc.CreatePersonalToken(c.Request.Context(), currentUserID(c), req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidScope):
+			c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_scope"})
+		case errors.Is(err, domain.ErrInvalidExpiry):
+			c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_expiry"})
+		default:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, dto.CreatedPersonalTokenResponse{
+		PersonalTokenResponse: toPersonalToken(t, time.Now()),
+		Token:                 raw,
+	})
+}
+
+// ListPersonalTokens возвращает персональные токены пользователя
+// @Summary      Персональные токены
+// @Description  Неотозванные токены текущего пользователя, включая истёкшие (expired=true). Сами токены не возвращаются.
+// @Tags         personal-tokens
+// @Produce      json
+// @Success      200  {array}   dto.PersonalTokenResponse
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /personal-tokens [get]
+func (h *AuthHandler) ListPersonalTokens(c *gin.Context) {
+	tokens, err := h.svc.ListPersonalTokens(c.Request.Context(), currentUserID(c))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		return
+	}
+
+	now := time.Now()
+	resp := make([]dto.PersonalTokenResponse, 0, len(tokens))
+	for _, t := range tokens {
+		resp = append(resp, toPersonalToken(t, now))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RevokePersonalToken отзывает персональный токен
+// @Summary      Отозвать персональный токен
+// @Tags         personal-tokens
+// @Param        id   path      string  true  "ID токена"
+// @Success      204  {string}  string  "Токен отозван, тело отсутствует"
+// @Failure      400  {object}  dto.ErrorResponse  "Некорректный ID"
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      404  {object}  dto.ErrorResponse  "Токен не найден"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /personal-tokens/{id} [delete]
+func (h *AuthHandler) RevokePersonalToken(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad_request"})
+		return
+	}
+
+	if err := h.svc.RevokePersonalToken(c.Request.Context(), currentUserID(c), id); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found"})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func toPersonalToken(t domain.PersonalToken, now time.Time) dto.PersonalTokenResponse {
+	return dto.PersonalTokenResponse{
+		ID:         t.ID.String(),
+		Name:       t.Name,
+		Scopes:     nonNil(t.Scopes),
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt:  t.CreatedAt,
+		Expired:    !now.Before(t.ExpiresAt),
+	}
+}
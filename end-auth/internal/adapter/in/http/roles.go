@@ -0,0 +1,136 @@
+package httpin
+
+import (
+	"errors"
+	"net/http"
+
+	"auth/internal/adapter/in/http/dto"
+	"auth/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListRoles возвращает список ролей
+// @Summary      Роли
+// @Tags         roles
+// @Produce      json
+// @Success      200  {array}   dto.RoleResponse
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /roles [get]
+func (h *AuthHandler) ListRoles(c *gin.Context) {
+	roles, err := h.svc.ListRoles(c.Request.Context())
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+		return
+	}
+
+	resp := make([]dto.RoleResponse, 0, len(roles))
+	for _, r := range roles {
+		resp = append(resp, dto.RoleResponse{Name: r.Name, Description: r.Description})
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// UserRoles возвращает роли пользователя
+// @Summary      Роли пользователя
+// @Description  Только для admin
+// @Tags         roles
+// @Produce      json
+// @Param        id   path      string  true  "ID пользователя"
+// @Success      200  {object}  dto.UserRolesResponse
+// @Failure      400  {object}  dto.ErrorResponse  "Некорректный ID"
+// @Failure      401  {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      403  {object}  dto.ErrorResponse  "Нет роли admin"
+// @Failure      404  {object}  dto.ErrorResponse  "Пользователь не найден"
+// @Failure      500  {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/roles [get]
+func (h *AuthHandler) UserRoles(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+
+	roles, err := h.svc.UserRoles(c.Request.Context(), userID)
+	if err != nil {
+		abortRoleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.UserRolesResponse{UserID: userID.String(), Roles: nonNil(roles)})
+}
+
+// GrantRole выдаёт роль пользователю
+// @Summary      Выдать роль
+// @Description  Только для admin. В access-токенах пользователя роль появится после следующего refresh; /validate видит её сразу.
+// @Tags         roles
+// @Param        id    path      string  true  "ID пользователя"
+// @Param        role  path      string  true  "Роль"
+// @Success      204   {string}  string             "Роль выдана, тело отсутствует"
+// @Failure      400   {object}  dto.ErrorResponse  "Некорректный ID или неизвестная роль"
+// @Failure      401   {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      403   {object}  dto.ErrorResponse  "Нет роли admin"
+// @Failure      404   {object}  dto.ErrorResponse  "Пользователь не найден"
+// @Failure      500   {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/roles/{role} [put]
+func (h *AuthHandler) GrantRole(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+
+	actor := currentUserID(c)
+	if err := h.svc.GrantRole(c.Request.Context(), &actor, userID, c.Param("role")); err != nil {
+		abortRoleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeRole забирает роль у пользователя
+// @Summary      Забрать роль
+// @Description  Только для admin
+// @Tags         roles
+// @Param        id    path      string  true  "ID пользователя"
+// @Param        role  path      string  true  "Роль"
+// @Success      204   {string}  string             "Роль отозвана, тело отсутствует"
+// @Failure      400   {object}  dto.ErrorResponse  "Некорректный ID или неизвестная роль"
+// @Failure      401   {object}  dto.ErrorResponse  "Нет токена или он невалиден"
+// @Failure      403   {object}  dto.ErrorResponse  "Нет роли admin"
+// @Failure      500   {object}  dto.ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/roles/{role} [delete]
+func (h *AuthHandler) RevokeRole(c *gin.Context) {
+	userID, ok := pathUserID(c)
+	if !ok {
+		return
+	}
+
+	actor := currentUserID(c)
+	if err := h.svc.RevokeRole(c.Request.Context(), &actor, userID, c.Param("role")); err != nil {
+		abortRoleError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func abortRoleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrUnknownRole):
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "unknown_role"})
+	case errors.Is(err, domain.ErrNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, dto.ErrorResponse{Error: "not_found"})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: "internal"})
+	}
+}
+
+// nonNil нужен, чтобы пустой список уходил в JSON как [], а не null.
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
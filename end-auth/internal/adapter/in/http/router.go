@@ -6,6 +6,7 @@ package httpin
 
 import (
 	_ "auth/docs"
+	"auth/internal/domain"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -17,18 +18,38 @@ import (
 // @version 1.0
 // @description Сервис авторизации
 // @BasePath /api/v1
-func NewGinRouter(h *AuthHandler) *gin.Engine {
+func NewGinRouter(h *AuthHandler, trustedProxies []string) (*gin.Engine, error) {
 	r := gin.New()
 	r.Use(gin.Recovery())
 
+	// без списка доверенных прокси X-Forwarded-For игнорируется и IP берётся из соединения
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, err
+	}
+	r.Use(requestClient)
+
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	r.GET("/.well-known/jwks.json", h.JWKS)
+
+	r.GET("/oauth/authorize", h.AuthorizeRedirect)
+	oauth := r.Group("/oauth", h.RequireClient)
+	{
+		oauth.POST("/token", h.Token)
+		oauth.POST("/introspect", h.Introspect)
+		oauth.POST("/revoke", h.RevokeToken)
+	}
 
 	a := r.Group("/api/v1")
 	{
 		a.POST("/register", h.Register)
 		a.POST("/login", h.Login)
+		a.POST("/login/mfa", h.LoginMFA)
+		a.POST("/login/magic/start", h.StartMagicLink)
+		a.POST("/login/magic/confirm", h.ConfirmMagicLink)
 		a.POST("/refresh", h.Refresh)
 		a.POST("/logout", h.Logout)
+		a.POST("/logout-all", h.RequireAuth, h.LogoutAll)
+		a.POST("/password/change", h.RequireAuth, h.ChangePassword)
 
 		pr := a.Group("/password/reset")
 		{
@@ -36,8 +57,72 @@ func NewGinRouter(h *AuthHandler) *gin.Engine {
 			pr.POST("/confirm", h.ConfirmReset)
 		}
 
+		ev := a.Group("/email/verify")
+		{
+			ev.POST("", h.VerifyEmail)
+			ev.POST("/resend", h.ResendVerification)
+		}
+
 		a.GET("/validate", h.Validate)
+
+		oidc := a.Group("/oidc")
+		{
+			oidc.GET("/providers", h.OIDCProviders)
+			oidc.GET("/:provider/start", h.StartOIDC)
+			oidc.GET("/:provider/callback", h.OIDCCallback)
+			oidc.POST("/exchange", h.OIDCExchange)
+		}
+		a.GET("/identities", h.RequireAuth, h.ListIdentities)
+
+		a.GET("/roles", h.RequireAuth, h.ListRoles)
+		a.GET("/security/activity", h.RequireAuth, h.SecurityActivity)
+
+		adm := a.Group("/admin", h.RequireAuth, RequireRoles(domain.RoleAdmin))
+		{
+			adm.GET("/users", h.AdminListUsers)
+			adm.GET("/users/:id", h.AdminGetUser)
+			adm.POST("/users/:id/block", h.AdminBlockUser)
+			adm.POST("/users/:id/unblock", h.AdminUnblockUser)
+			adm.GET("/users/:id/blocks", h.AdminUserBlocks)
+			adm.POST("/users/:id/force-password-reset", h.AdminForcePasswordReset)
+			adm.POST("/users/:id/revoke-sessions", h.AdminRevokeSessions)
+			adm.GET("/events", h.AdminEvents)
+
+			adm.GET("/users/:id/roles", h.UserRoles)
+			adm.PUT("/users/:id/roles/:role", h.GrantRole)
+			adm.DELETE("/users/:id/roles/:role", h.RevokeRole)
+		}
+
+		mfa := a.Group("/mfa", h.RequireAuth)
+		{
+			mfa.GET("", h.MFAStatus)
+			mfa.POST("/totp/enroll", h.EnrollTOTP)
+			mfa.POST("/totp/confirm", h.ConfirmTOTP)
+			mfa.POST("/totp/disable", h.DisableTOTP)
+			mfa.POST("/recovery-codes", h.RegenerateRecoveryCodes)
+		}
+
+		sess := a.Group("/sessions", h.RequireAuth)
+		{
+			sess.GET("", h.ListSessions)
+			sess.DELETE("/:id", h.RevokeSession)
+		}
+
+		pat := a.Group("/personal-tokens", h.RequireAuth)
+		{
+			pat.GET("", h.ListPersonalTokens)
+			pat.POST("", h.CreatePersonalToken)
+			pat.DELETE("/:id", h.RevokePersonalToken)
+		}
+
+		apps := a.Group("/oauth", h.RequireAuth)
+		{
+			apps.GET("/authorize", h.AuthorizePrompt)
+			apps.POST("/authorize", h.AuthorizeDecision)
+			apps.GET("/apps", h.ListAuthorizedApps)
+			apps.DELETE("/apps/:client_id", h.RevokeAuthorizedApp)
+		}
 	}
 
-	return r
+	return r, nil
 }
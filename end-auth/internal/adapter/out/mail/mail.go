@@ -0,0 +1,125 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"auth/internal/domain"
+)
+
+const (
+	DriverSMTP   = "smtp"
+	DriverOutbox = "outbox"
+)
+
+type Config struct {
+	Driver string       `mapstructure:"driver" validate:"required,oneof=smtp outbox"`
+	From   string       `mapstructure:"from" default:"Enduran <no-reply@enduran.local>"`
+	SMTP   SMTPConfig   `mapstructure:"smtp"`
+	Outbox OutboxConfig `mapstructure:"outbox"`
+}
+
+// New создаёт адаптер доставки по конфигу. Драйвер задаётся явно: outbox письма
+// никому не доставляет, поэтому разрешён только при devMode.
+func New(cfg Config, devMode bool) (domain.Mailer, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		if cfg.SMTP.Host == "" {
+			return nil, fmt.Errorf("mail.smtp.host is required for driver %q", DriverSMTP)
+		}
+		return &SMTPMailer{cfg: cfg.SMTP, from: cfg.From}, nil
+	case DriverOutbox:
+		if !devMode {
+			return nil, fmt.Errorf("mail driver %q is allowed only with svc.devMode", DriverOutbox)
+		}
+		return &OutboxMailer{dir: cfg.Outbox.Dir, from: cfg.From}, nil
+	case "":
+		return nil, fmt.Errorf("mail.driver is required (%s or %s)", DriverSMTP, DriverOutbox)
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
+
+// buildMessage собирает письмо в формате RFC 5322 (multipart/alternative, если есть HTML).
+func buildMessage(from string, m domain.Mail, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	h := textproto.MIMEHeader{}
+	h.Set("From", from)
+	h.Set("To", m.To)
+	h.Set("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	h.Set("Date", now.Format(time.RFC1123Z))
+	h.Set("Message-ID", messageID(from))
+	h.Set("MIME-Version", "1.0")
+
+	if m.HTML == "" {
+		h.Set("Content-Type", `text/plain; charset="utf-8"`)
+		h.Set("Content-Transfer-Encoding", "quoted-printable")
+		writeHeader(&buf, h)
+		if err := writeQP(&buf, m.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var parts bytes.Buffer
+	mw := multipart.NewWriter(&parts)
+	h.Set("Content-Type", `multipart/alternative; boundary="`+mw.Boundary()+`"`)
+	writeHeader(&buf, h)
+
+	for _, part := range []struct{ ctype, content string }{
+		{`text/plain; charset="utf-8"`, m.Text},
+		{`text/html; charset="utf-8"`, m.HTML},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.ctype},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQP(pw, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	buf.Write(parts.Bytes())
+	return buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, h textproto.MIMEHeader) {
+	for _, k := range []string{"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type", "Content-Transfer-Encoding"} {
+		if v := h.Get(k); v != "" {
+			fmt.Fprintf(buf, "%s: %s\r\n", k, v)
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+func writeQP(w io.Writer, s string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(s)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func messageID(from string) string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	domainPart := "localhost"
+	if addr := from[strings.LastIndex(from, "@")+1:]; addr != from {
+		domainPart = strings.TrimRight(addr, ">")
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domainPart + ">"
+}
@@ -0,0 +1,52 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"auth/internal/domain"
+
+	"github.com/rs/zerolog/log"
+)
+
+type OutboxConfig struct {
+	Dir string `mapstructure:"dir" default:"outbox"`
+}
+
+// OutboxMailer складывает письма в каталог в виде .eml-файлов — для локальной разработки.
+type OutboxMailer struct {
+	dir  string
+	from string
+}
+
+func (m *OutboxMailer) Send(ctx context.Context, msg domain.Mail) error {
+	now := time.Now()
+	raw, err := buildMessage(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("operation", "mail.OutboxMailer.Send").
+		Str("to", msg.To).
+		Str("subject", msg.Subject).
+		Str("file", path).
+		Msg("mail written to outbox")
+	return nil
+}
@@ -0,0 +1,262 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"auth/internal/domain"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AuthMethodPost  = "client_secret_post"
+	AuthMethodBasic = "client_secret_basic"
+)
+
+type Config struct {
+	Providers []ProviderConfig `mapstructure:"providers"`
+}
+
+// ProviderConfig описывает OIDC-провайдера. Эндпоинты берутся из discovery
+// (<issuer>/.well-known/openid-configuration); явно заданные URL имеют приоритет.
+type ProviderConfig struct {
+	Name         string   `mapstructure:"name"`
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"clientID"`
+	ClientSecret string   `mapstructure:"clientSecret"`
+	Scopes       []string `mapstructure:"scopes"`
+	AuthMethod   string   `mapstructure:"authMethod"`
+	AuthURL      string   `mapstructure:"authURL"`
+	TokenURL     string   `mapstructure:"tokenURL"`
+	JWKSURL      string   `mapstructure:"jwksURL"`
+	// TrustEmail разрешает привязку к существующему аккаунту по email, подтверждённому провайдером.
+	TrustEmail bool `mapstructure:"trustEmail"`
+}
+
+// New создаёт провайдеров из конфига.
+func New(cfg Config) ([]domain.IdentityProvider, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	seen := map[string]bool{}
+	out := make([]domain.IdentityProvider, 0, len(cfg.Providers))
+	for _, pc := range cfg.Providers {
+		if pc.Name == "" || pc.Issuer == "" || pc.ClientID == "" {
+			return nil, fmt.Errorf("oidc provider %q: name, issuer and clientID are required", pc.Name)
+		}
+		if seen[pc.Name] {
+			return nil, fmt.Errorf("oidc provider %q is configured twice", pc.Name)
+		}
+		seen[pc.Name] = true
+		if len(pc.Scopes) == 0 {
+			pc.Scopes = []string{"openid", "email"}
+		}
+		if pc.AuthMethod == "" {
+			pc.AuthMethod = AuthMethodPost
+		}
+		out = append(out, &Provider{cfg: pc, client: client, keys: &keySet{client: client}})
+	}
+	return out, nil
+}
+
+type Provider struct {
+	cfg    ProviderConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	endpoints *endpoints
+	keys      *keySet
+}
+
+type endpoints struct {
+	Issuer   string `json:"issuer"`
+	AuthURL  string `json:"authorization_endpoint"`
+	TokenURL string `json:"token_endpoint"`
+	JWKSURL  string `json:"jwks_uri"`
+}
+
+func (p *Provider) Name() string     { return p.cfg.Name }
+func (p *Provider) TrustEmail() bool { return p.cfg.TrustEmail }
+
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge, redirectURI string) (string, error) {
+	ep, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(ep.AuthURL, "?") {
+		sep = "&"
+	}
+	return ep.AuthURL + sep + q.Encode(), nil
+}
+
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, redirectURI, nonce string) (domain.ExternalIdentity, error) {
+	ep, err := p.discover(ctx)
+	if err != nil {
+		return domain.ExternalIdentity{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("code_verifier", codeVerifier)
+	if p.cfg.AuthMethod != AuthMethodBasic {
+		form.Set("client_id", p.cfg.ClientID)
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return domain.ExternalIdentity{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.AuthMethod == AuthMethodBasic {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return domain.ExternalIdentity{}, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return domain.ExternalIdentity{}, fmt.Errorf("oidc %s: token endpoint returned %d: %s", p.cfg.Name, resp.StatusCode, truncate(body, 200))
+	}
+	var tok struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return domain.ExternalIdentity{}, fmt.Errorf("oidc %s: bad token response: %w", p.cfg.Name, err)
+	}
+	if tok.IDToken == "" {
+		return domain.ExternalIdentity{}, fmt.Errorf("oidc %s: token response has no id_token", p.cfg.Name)
+	}
+	return p.verifyIDToken(ctx, ep, tok.IDToken, nonce)
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified any    `json:"email_verified"`
+}
+
+func (p *Provider) verifyIDToken(ctx context.Context, ep *endpoints, raw, nonce string) (domain.ExternalIdentity, error) {
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.keys.key(ctx, ep.JWKSURL, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "EdDSA"}),
+		jwt.WithIssuer(ep.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return domain.ExternalIdentity{}, fmt.Errorf("oidc %s: invalid id_token: %w", p.cfg.Name, err)
+	}
+	if claims.Nonce == "" || claims.Nonce != nonce {
+		return domain.ExternalIdentity{}, fmt.Errorf("oidc %s: id_token nonce mismatch", p.cfg.Name)
+	}
+	if claims.Subject == "" {
+		return domain.ExternalIdentity{}, fmt.Errorf("oidc %s: id_token has no sub", p.cfg.Name)
+	}
+
+	return domain.ExternalIdentity{
+		Provider:      p.cfg.Name,
+		Subject:       claims.Subject,
+		Email:         strings.ToLower(strings.TrimSpace(claims.Email)),
+		EmailVerified: isTrue(claims.EmailVerified),
+	}, nil
+}
+
+// discover читает метаданные провайдера один раз и кэширует их.
+func (p *Provider) discover(ctx context.Context) (*endpoints, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.endpoints != nil {
+		return p.endpoints, nil
+	}
+
+	ep := &endpoints{Issuer: p.cfg.Issuer, AuthURL: p.cfg.AuthURL, TokenURL: p.cfg.TokenURL, JWKSURL: p.cfg.JWKSURL}
+	if ep.AuthURL == "" || ep.TokenURL == "" || ep.JWKSURL == "" {
+		var meta endpoints
+		u := strings.TrimRight(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+		if err := getJSON(ctx, p.client, u, &meta); err != nil {
+			return nil, fmt.Errorf("oidc %s: discovery: %w", p.cfg.Name, err)
+		}
+		if meta.Issuer != "" && meta.Issuer != p.cfg.Issuer {
+			return nil, fmt.Errorf("oidc %s: discovery issuer %q does not match configured %q", p.cfg.Name, meta.Issuer, p.cfg.Issuer)
+		}
+		ep.AuthURL = firstNonEmpty(ep.AuthURL, meta.AuthURL)
+		ep.TokenURL = firstNonEmpty(ep.TokenURL, meta.TokenURL)
+		ep.JWKSURL = firstNonEmpty(ep.JWKSURL, meta.JWKSURL)
+	}
+	if ep.AuthURL == "" || ep.TokenURL == "" || ep.JWKSURL == "" {
+		return nil, errors.New("oidc " + p.cfg.Name + ": provider endpoints are not known")
+	}
+	p.endpoints = ep
+	return ep, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// isTrue понимает email_verified и как bool, и как строку — некоторые провайдеры отдают "true".
+func isTrue(v any) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b == "true"
+	}
+	return false
+}
+
+func firstNonEmpty(a, b string) string {
+	if a != "" {
+		return a
+	}
+	return b
+}
+
+func truncate(b []byte, n int) string {
+	if len(b) > n {
+		b = b[:n]
+	}
+	return string(b)
+}
@@ -3,7 +3,11 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"auth/internal/adapter/out/postgres/gen"
@@ -13,22 +17,47 @@ import (
 	"github.com/jackc/pgconn"
 	"github.com/lib/pq"
 	"github.com/rs/zerolog/log"
+	"github.com/sqlc-dev/pqtype"
 )
 
 /* ========== агрегатор ========== */
 
 type Repositories struct {
-	User    domain.UserRepository
-	Refresh domain.RefreshRepository
-	Reset   domain.PasswordResetRepository
+	User     domain.UserRepository
+	Refresh  domain.RefreshRepository
+	Reset    domain.PasswordResetRepository
+	Event    domain.EventRepository
+	Login    domain.LoginThrottleRepository
+	MFA      domain.MFARepository
+	Role     domain.RoleRepository
+	Identity domain.IdentityRepository
+	OIDC     domain.OIDCRequestRepository
+	Revoked  domain.RevokedTokenRepository
+	Client   domain.OAuthClientRepository
+	Code     domain.AuthorizationCodeRepository
+	Consent  domain.ConsentRepository
+	PAT      domain.PersonalTokenRepository
+	Magic    domain.MagicLinkRepository
 }
 
 func NewRepositories(db *sql.DB) *Repositories {
 	q := gen.New(db)
 	return &Repositories{
-		User:    &userRepo{q: q},
-		Refresh: &refreshRepo{q: q},
-		Reset:   &resetRepo{q: q},
+		User:     &userRepo{q: q},
+		Refresh:  &refreshRepo{q: q},
+		Reset:    &resetRepo{q: q},
+		Event:    &eventRepo{q: q},
+		Login:    &loginThrottleRepo{q: q},
+		MFA:      &mfaRepo{q: q},
+		Role:     &roleRepo{q: q},
+		Identity: &identityRepo{q: q},
+		OIDC:     &oidcRequestRepo{q: q},
+		Revoked:  &revokedTokenRepo{q: q},
+		Client:   &oauthClientRepo{q: q},
+		Code:     &authCodeRepo{q: q},
+		Consent:  &consentRepo{q: q},
+		PAT:      &personalTokenRepo{q: q},
+		Magic:    &magicLinkRepo{q: q},
 	}
 }
 
@@ -42,6 +71,21 @@ func ptrTime(nt sql.NullTime) *time.Time {
 	return nil
 }
 
+func ptrUUID(nu uuid.NullUUID) *uuid.UUID {
+	if nu.Valid {
+		id := nu.UUID
+		return &id
+	}
+	return nil
+}
+
+func nullUUID(id *uuid.UUID) uuid.NullUUID {
+	if id == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: *id, Valid: true}
+}
+
 func optString(v any) *string {
 	switch x := v.(type) {
 	case sql.NullString:
@@ -49,6 +93,11 @@ func optString(v any) *string {
 			s := x.String
 			return &s
 		}
+	case pqtype.Inet:
+		if x.Valid {
+			s := x.IPNet.IP.String()
+			return &s
+		}
 	case string:
 		if x != "" {
 			return &x
@@ -57,6 +106,28 @@ func optString(v any) *string {
 	return nil
 }
 
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func inet(s *string) pqtype.Inet {
+	if s == nil {
+		return pqtype.Inet{}
+	}
+	ip := net.ParseIP(*s)
+	if ip == nil {
+		return pqtype.Inet{}
+	}
+	bits := 8 * net.IPv6len
+	if v4 := ip.To4(); v4 != nil {
+		ip, bits = v4, 8*net.IPv4len
+	}
+	return pqtype.Inet{IPNet: net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, Valid: true}
+}
+
 func mapNotFound(err error) error {
 	if errors.Is(err, sql.ErrNoRows) {
 		return domain.ErrNotFound
@@ -77,6 +148,23 @@ func isUniqueViolation(err error) bool {
 
 type userRepo struct{ q gen.Querier }
 
+func toUser(u gen.User) domain.User {
+	return domain.User{
+		ID:                u.ID,
+		Email:             u.Email,
+		PasswordHash:      u.PasswordHash,
+		IsBlocked:         u.IsBlocked,
+		LastLoginAt:       ptrTime(u.LastLoginAt),
+		EmailVerifiedAt:   ptrTime(u.EmailVerifiedAt),
+		BlockedReason:     optString(u.BlockedReason),
+		BlockedUntil:      ptrTime(u.BlockedUntil),
+		MustResetPassword: u.MustResetPassword,
+		TokensValidAfter:  ptrTime(u.TokensValidAfter),
+		CreatedAt:         u.CreatedAt,
+		UpdatedAt:         u.UpdatedAt,
+	}
+}
+
 func (r *userRepo) Create(ctx context.Context, email, passwordHash string) (domain.User, error) {
 	u, err := r.q.CreateUser(ctx, gen.CreateUserParams{
 		Email:        email,
@@ -105,15 +193,7 @@ func (r *userRepo) Create(ctx context.Context, email, passwordHash string) (doma
 		Time("created_at", u.CreatedAt).
 		Msg("user created")
 
-	return domain.User{
-		ID:           u.ID,
-		Email:        u.Email,
-		PasswordHash: u.PasswordHash,
-		IsBlocked:    u.IsBlocked,
-		LastLoginAt:  ptrTime(u.LastLoginAt),
-		CreatedAt:    u.CreatedAt,
-		UpdatedAt:    u.UpdatedAt,
-	}, nil
+	return toUser(u), nil
 }
 
 func (r *userRepo) ByEmail(ctx context.Context, email string) (domain.User, error) {
@@ -140,15 +220,7 @@ func (r *userRepo) ByEmail(ctx context.Context, email string) (domain.User, erro
 		Str("email", u.Email).
 		Msg("user fetched")
 
-	return domain.User{
-		ID:           u.ID,
-		Email:        u.Email,
-		PasswordHash: u.PasswordHash,
-		IsBlocked:    u.IsBlocked,
-		LastLoginAt:  ptrTime(u.LastLoginAt),
-		CreatedAt:    u.CreatedAt,
-		UpdatedAt:    u.UpdatedAt,
-	}, nil
+	return toUser(u), nil
 }
 
 func (r *userRepo) ByID(ctx context.Context, id uuid.UUID) (domain.User, error) {
@@ -175,21 +247,14 @@ func (r *userRepo) ByID(ctx context.Context, id uuid.UUID) (domain.User, error)
 		Str("email", u.Email).
 		Msg("user fetched")
 
-	return domain.User{
-		ID:           u.ID,
-		Email:        u.Email,
-		PasswordHash: u.PasswordHash,
-		IsBlocked:    u.IsBlocked,
-		LastLoginAt:  ptrTime(u.LastLoginAt),
-		CreatedAt:    u.CreatedAt,
-		UpdatedAt:    u.UpdatedAt,
-	}, nil
+	return toUser(u), nil
 }
 
-func (r *userRepo) UpdatePassword(ctx context.Context, id uuid.UUID, newHash string) error {
+func (r *userRepo) UpdatePassword(ctx context.Context, id uuid.UUID, newHash string, keepHistory int) error {
 	if err := r.q.UpdatePassword(ctx, gen.UpdatePasswordParams{
 		ID:           id,
 		PasswordHash: newHash,
+		KeepHistory:  int32(keepHistory),
 	}); err != nil {
 		log.Error().
 			Err(err).
@@ -206,6 +271,45 @@ func (r *userRepo) UpdatePassword(ctx context.Context, id uuid.UUID, newHash str
 	return nil
 }
 
+func (r *userRepo) PasswordHistory(ctx context.Context, id uuid.UUID, limit int) ([]string, error) {
+	hashes, err := r.q.ListPasswordHistory(ctx, gen.ListPasswordHistoryParams{
+		UserID: id,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "users.PasswordHistory").
+			Str("user_id", id.String()).
+			Msg("failed to list password history")
+		return nil, err
+	}
+	return hashes, nil
+}
+
+func (r *userRepo) RehashPassword(ctx context.Context, id uuid.UUID, oldHash, newHash string) error {
+	n, err := r.q.RehashPassword(ctx, gen.RehashPasswordParams{
+		ID:             id,
+		PasswordHash:   oldHash,
+		PasswordHash_2: newHash,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "users.RehashPassword").
+			Str("user_id", id.String()).
+			Msg("failed to rehash password")
+		return err
+	}
+
+	log.Debug().
+		Str("operation", "users.RehashPassword").
+		Str("user_id", id.String()).
+		Bool("updated", n > 0).
+		Msg("password rehashed")
+	return nil
+}
+
 func (r *userRepo) SetLastLogin(ctx context.Context, id uuid.UUID, t time.Time) error {
 	if err := r.q.SetLastLogin(ctx, gen.SetLastLoginParams{
 		ID:          id,
@@ -227,44 +331,220 @@ func (r *userRepo) SetLastLogin(ctx context.Context, id uuid.UUID, t time.Time)
 	return nil
 }
 
-func (r *userRepo) SetBlocked(ctx context.Context, id uuid.UUID, blocked bool) error {
-	if err := r.q.SetBlocked(ctx, gen.SetBlockedParams{
-		ID:        id,
-		IsBlocked: blocked,
+func (r *userRepo) SetTokensValidAfter(ctx context.Context, id uuid.UUID, t time.Time) error {
+	if err := r.q.SetTokensValidAfter(ctx, gen.SetTokensValidAfterParams{
+		ID:               id,
+		TokensValidAfter: sql.NullTime{Time: t, Valid: true},
+	}); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "users.SetTokensValidAfter").
+			Str("user_id", id.String()).
+			Msg("failed to set tokens_valid_after")
+		return err
+	}
+
+	log.Info().
+		Str("operation", "users.SetTokensValidAfter").
+		Str("user_id", id.String()).
+		Time("tokens_valid_after", t).
+		Msg("access tokens revoked")
+	return nil
+}
+
+func (r *userRepo) Block(ctx context.Context, id uuid.UUID, until *time.Time, reason string, actor *uuid.UUID) error {
+	var blockedUntil sql.NullTime
+	if until != nil {
+		blockedUntil = sql.NullTime{Time: *until, Valid: true}
+	}
+	if err := r.q.BlockUser(ctx, gen.BlockUserParams{
+		UserID:       id,
+		ActorID:      nullUUID(actor),
+		Reason:       reason,
+		BlockedUntil: blockedUntil,
+	}); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "users.Block").
+			Str("user_id", id.String()).
+			Msg("failed to block user")
+		return err
+	}
+
+	log.Debug().
+		Str("operation", "users.Block").
+		Str("user_id", id.String()).
+		Msg("user blocked")
+	return nil
+}
+
+func (r *userRepo) Unblock(ctx context.Context, id uuid.UUID, actor *uuid.UUID, reason *string) error {
+	if err := r.q.UnblockUser(ctx, gen.UnblockUserParams{
+		UserID:     id,
+		ActorID:    nullUUID(actor),
+		LiftReason: nullString(reason),
 	}); err != nil {
 		log.Error().
 			Err(err).
-			Str("operation", "users.SetBlocked").
+			Str("operation", "users.Unblock").
 			Str("user_id", id.String()).
-			Bool("blocked", blocked).
-			Msg("failed to set is_blocked")
+			Msg("failed to unblock user")
 		return err
 	}
 
 	log.Debug().
-		Str("operation", "users.SetBlocked").
+		Str("operation", "users.Unblock").
 		Str("user_id", id.String()).
-		Bool("blocked", blocked).
-		Msg("is_blocked set")
+		Msg("user unblocked")
+	return nil
+}
+
+func (r *userRepo) ListBlocks(ctx context.Context, id uuid.UUID) ([]domain.UserBlock, error) {
+	rows, err := r.q.ListUserBlocks(ctx, id)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "users.ListBlocks").
+			Str("user_id", id.String()).
+			Msg("failed to list user blocks")
+		return nil, err
+	}
+
+	out := make([]domain.UserBlock, 0, len(rows))
+	for _, b := range rows {
+		out = append(out, domain.UserBlock{
+			ID:           b.ID,
+			UserID:       b.UserID,
+			ActorID:      ptrUUID(b.ActorID),
+			Reason:       b.Reason,
+			BlockedUntil: ptrTime(b.BlockedUntil),
+			BlockedAt:    b.BlockedAt,
+			LiftedAt:     ptrTime(b.LiftedAt),
+			LiftedBy:     ptrUUID(b.LiftedBy),
+			LiftReason:   optString(b.LiftReason),
+		})
+	}
+	return out, nil
+}
+
+func (r *userRepo) RequirePasswordReset(ctx context.Context, id uuid.UUID) error {
+	if err := r.q.RequirePasswordReset(ctx, id); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "users.RequirePasswordReset").
+			Str("user_id", id.String()).
+			Msg("failed to require password reset")
+		return err
+	}
 	return nil
 }
 
+func (r *userRepo) Search(ctx context.Context, f domain.UserFilter) ([]domain.User, int, error) {
+	var query sql.NullString
+	if f.Query != "" {
+		query = sql.NullString{String: likeEscaper.Replace(f.Query), Valid: true}
+	}
+	var blocked sql.NullBool
+	if f.Blocked != nil {
+		blocked = sql.NullBool{Bool: *f.Blocked, Valid: true}
+	}
+
+	rows, err := r.q.SearchUsers(ctx, gen.SearchUsersParams{
+		Query:      query,
+		Blocked:    blocked,
+		PageLimit:  int32(f.Limit),
+		PageOffset: int32(f.Offset),
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "users.Search").
+			Msg("failed to search users")
+		return nil, 0, err
+	}
+	total, err := r.q.CountUsers(ctx, gen.CountUsersParams{Query: query, Blocked: blocked})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "users.Search").
+			Msg("failed to count users")
+		return nil, 0, err
+	}
+
+	out := make([]domain.User, 0, len(rows))
+	for _, u := range rows {
+		out = append(out, toUser(u))
+	}
+	return out, int(total), nil
+}
+
+// likeEscaper экранирует спецсимволы LIKE, чтобы поиск шёл по подстроке буквально.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *userRepo) MarkEmailVerified(ctx context.Context, id uuid.UUID, email string) (bool, error) {
+	n, err := r.q.MarkEmailVerified(ctx, gen.MarkEmailVerifiedParams{
+		ID:    id,
+		Email: email,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "users.MarkEmailVerified").
+			Str("user_id", id.String()).
+			Msg("failed to mark email verified")
+		return false, err
+	}
+
+	log.Debug().
+		Str("operation", "users.MarkEmailVerified").
+		Str("user_id", id.String()).
+		Int64("rows", n).
+		Msg("email verification applied")
+	return n > 0, nil
+}
+
 /* ================== refresh_sessions ================== */
 
 type refreshRepo struct{ q gen.Querier }
 
-func (r *refreshRepo) Create(ctx context.Context, userID uuid.UUID, tokenHash []byte, ua, ip *string, exp time.Time) (domain.RefreshSession, error) {
+func toRefreshSession(rs gen.RefreshSession) domain.RefreshSession {
+	return domain.RefreshSession{
+		ID:         rs.ID,
+		UserID:     rs.UserID,
+		FamilyID:   rs.FamilyID,
+		ParentID:   ptrUUID(rs.ParentID),
+		TokenHash:  rs.TokenHash,
+		UserAgent:  optString(rs.UserAgent),
+		IP:         optString(rs.Ip),
+		DeviceName: optString(rs.DeviceName),
+		ClientID:   optString(rs.ClientID),
+		Scopes:     rs.Scopes,
+		CreatedAt:  rs.CreatedAt,
+		ExpiresAt:  rs.ExpiresAt,
+		RevokedAt:  ptrTime(rs.RevokedAt),
+	}
+}
+
+func (r *refreshRepo) Create(ctx context.Context, s domain.RefreshSession) (domain.RefreshSession, error) {
 	rs, err := r.q.CreateRefreshSession(ctx, gen.CreateRefreshSessionParams{
-		UserID:    userID,
-		TokenHash: tokenHash,
-		ExpiresAt: exp,
+		UserID:     s.UserID,
+		FamilyID:   s.FamilyID,
+		ParentID:   nullUUID(s.ParentID),
+		TokenHash:  s.TokenHash,
+		UserAgent:  nullString(s.UserAgent),
+		Ip:         inet(s.IP),
+		DeviceName: nullString(s.DeviceName),
+		ClientID:   nullString(s.ClientID),
+		Scopes:     s.Scopes,
+		ExpiresAt:  s.ExpiresAt,
 	})
 	if err != nil {
 		log.Error().
 			Err(err).
 			Str("operation", "refresh.Create").
-			Str("user_id", userID.String()).
-			Time("expires_at", exp).
+			Str("user_id", s.UserID.String()).
+			Str("family_id", s.FamilyID.String()).
+			Time("expires_at", s.ExpiresAt).
 			Msg("failed to create refresh session")
 		return domain.RefreshSession{}, err
 	}
@@ -273,6 +553,7 @@ func (r *refreshRepo) Create(ctx context.Context, userID uuid.UUID, tokenHash []
 		Str("operation", "refresh.Create").
 		Str("session_id", rs.ID.String()).
 		Str("user_id", rs.UserID.String()).
+		Str("family_id", rs.FamilyID.String()).
 		Time("expires_at", rs.ExpiresAt)
 	if uaV := optString(rs.UserAgent); uaV != nil {
 		ev = ev.Str("user_agent", *uaV)
@@ -282,16 +563,7 @@ func (r *refreshRepo) Create(ctx context.Context, userID uuid.UUID, tokenHash []
 	}
 	ev.Msg("refresh session created")
 
-	return domain.RefreshSession{
-		ID:        rs.ID,
-		UserID:    rs.UserID,
-		TokenHash: rs.TokenHash,
-		UserAgent: optString(rs.UserAgent),
-		IP:        optString(rs.Ip),
-		CreatedAt: rs.CreatedAt,
-		ExpiresAt: rs.ExpiresAt,
-		RevokedAt: ptrTime(rs.RevokedAt),
-	}, nil
+	return toRefreshSession(rs), nil
 }
 
 func (r *refreshRepo) ByHashActive(ctx context.Context, tokenHash []byte, _ time.Time) (domain.RefreshSession, error) {
@@ -323,16 +595,69 @@ func (r *refreshRepo) ByHashActive(ctx context.Context, tokenHash []byte, _ time
 	}
 	ev.Msg("refresh session fetched")
 
-	return domain.RefreshSession{
-		ID:        rs.ID,
-		UserID:    rs.UserID,
-		TokenHash: rs.TokenHash,
-		UserAgent: optString(rs.UserAgent),
-		IP:        optString(rs.Ip),
-		CreatedAt: rs.CreatedAt,
-		ExpiresAt: rs.ExpiresAt,
-		RevokedAt: ptrTime(rs.RevokedAt),
-	}, nil
+	return toRefreshSession(rs), nil
+}
+
+func (r *refreshRepo) ByHash(ctx context.Context, tokenHash []byte) (domain.RefreshSession, error) {
+	rs, err := r.q.GetRefreshByHash(ctx, tokenHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn().
+				Str("operation", "refresh.ByHash").
+				Msg("refresh not found")
+		} else {
+			log.Error().
+				Err(err).
+				Str("operation", "refresh.ByHash").
+				Msg("failed to get refresh by hash")
+		}
+		return domain.RefreshSession{}, mapNotFound(err)
+	}
+
+	log.Debug().
+		Str("operation", "refresh.ByHash").
+		Str("session_id", rs.ID.String()).
+		Str("user_id", rs.UserID.String()).
+		Str("family_id", rs.FamilyID.String()).
+		Bool("revoked", rs.RevokedAt.Valid).
+		Msg("refresh session fetched")
+
+	return toRefreshSession(rs), nil
+}
+
+func (r *refreshRepo) Rotate(ctx context.Context, id uuid.UUID) (bool, error) {
+	n, err := r.q.RotateRefreshByID(ctx, id)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "refresh.Rotate").
+			Str("session_id", id.String()).
+			Msg("failed to rotate refresh session")
+		return false, err
+	}
+
+	log.Debug().
+		Str("operation", "refresh.Rotate").
+		Str("session_id", id.String()).
+		Bool("rotated", n > 0).
+		Msg("refresh session rotated")
+	return n > 0, nil
+}
+
+func (r *refreshRepo) FamilyActive(ctx context.Context, familyID, userID uuid.UUID) (bool, error) {
+	active, err := r.q.IsRefreshFamilyActive(ctx, gen.IsRefreshFamilyActiveParams{
+		FamilyID: familyID,
+		UserID:   userID,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "refresh.FamilyActive").
+			Str("family_id", familyID.String()).
+			Msg("failed to check refresh family")
+		return false, err
+	}
+	return active, nil
 }
 
 func (r *refreshRepo) RevokeByID(ctx context.Context, id uuid.UUID) error {
@@ -352,6 +677,137 @@ func (r *refreshRepo) RevokeByID(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (r *refreshRepo) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	if err := r.q.RevokeRefreshFamily(ctx, familyID); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "refresh.RevokeFamily").
+			Str("family_id", familyID.String()).
+			Msg("failed to revoke refresh family")
+		return err
+	}
+
+	log.Debug().
+		Str("operation", "refresh.RevokeFamily").
+		Str("family_id", familyID.String()).
+		Msg("refresh family revoked")
+	return nil
+}
+
+func (r *refreshRepo) RevokeFamilyForUser(ctx context.Context, familyID, userID uuid.UUID) (bool, error) {
+	n, err := r.q.RevokeRefreshFamilyForUser(ctx, gen.RevokeRefreshFamilyForUserParams{
+		FamilyID: familyID,
+		UserID:   userID,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "refresh.RevokeFamilyForUser").
+			Str("family_id", familyID.String()).
+			Str("user_id", userID.String()).
+			Msg("failed to revoke refresh family")
+		return false, err
+	}
+
+	log.Debug().
+		Str("operation", "refresh.RevokeFamilyForUser").
+		Str("family_id", familyID.String()).
+		Str("user_id", userID.String()).
+		Int64("revoked", n).
+		Msg("refresh family revoked")
+	return n > 0, nil
+}
+
+func (r *refreshRepo) RevokeOthersForUser(ctx context.Context, userID, keepFamilyID uuid.UUID) error {
+	if err := r.q.RevokeOtherRefreshForUser(ctx, gen.RevokeOtherRefreshForUserParams{
+		UserID:   userID,
+		FamilyID: keepFamilyID,
+	}); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "refresh.RevokeOthersForUser").
+			Str("user_id", userID.String()).
+			Str("keep_family_id", keepFamilyID.String()).
+			Msg("failed to revoke other refresh sessions for user")
+		return err
+	}
+
+	log.Debug().
+		Str("operation", "refresh.RevokeOthersForUser").
+		Str("user_id", userID.String()).
+		Str("keep_family_id", keepFamilyID.String()).
+		Msg("other refresh sessions revoked for user")
+	return nil
+}
+
+func (r *refreshRepo) ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]domain.DeviceSession, error) {
+	rows, err := r.q.ListActiveRefreshForUser(ctx, userID)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "refresh.ListActiveByUser").
+			Str("user_id", userID.String()).
+			Msg("failed to list active refresh sessions")
+		return nil, err
+	}
+
+	out := make([]domain.DeviceSession, 0, len(rows))
+	for _, rs := range rows {
+		out = append(out, domain.DeviceSession{
+			ID:           rs.FamilyID,
+			UserAgent:    optString(rs.UserAgent),
+			IP:           optString(rs.Ip),
+			DeviceName:   optString(rs.DeviceName),
+			SignedInAt:   rs.SignedInAt,
+			LastActiveAt: rs.CreatedAt,
+			ExpiresAt:    rs.ExpiresAt,
+		})
+	}
+
+	log.Debug().
+		Str("operation", "refresh.ListActiveByUser").
+		Str("user_id", userID.String()).
+		Int("count", len(out)).
+		Msg("active refresh sessions listed")
+	return out, nil
+}
+
+func (r *refreshRepo) RevokeForUserClient(ctx context.Context, userID uuid.UUID, clientID string) ([]uuid.UUID, error) {
+	families, err := r.q.RevokeRefreshForUserClient(ctx, gen.RevokeRefreshForUserClientParams{
+		UserID:   userID,
+		ClientID: nullString(&clientID),
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "refresh.RevokeForUserClient").
+			Str("user_id", userID.String()).
+			Str("client_id", clientID).
+			Msg("failed to revoke app refresh sessions")
+		return nil, err
+	}
+
+	log.Debug().
+		Str("operation", "refresh.RevokeForUserClient").
+		Str("user_id", userID.String()).
+		Str("client_id", clientID).
+		Int("revoked", len(families)).
+		Msg("app refresh sessions revoked")
+	return families, nil
+}
+
+func (r *refreshRepo) RevokeForClient(ctx context.Context, clientID string) error {
+	if err := r.q.RevokeRefreshForClient(ctx, nullString(&clientID)); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "refresh.RevokeForClient").
+			Str("client_id", clientID).
+			Msg("failed to revoke refresh sessions of client")
+		return err
+	}
+	return nil
+}
+
 func (r *refreshRepo) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
 	if err := r.q.RevokeAllRefreshForUser(ctx, userID); err != nil {
 		log.Error().
@@ -373,6 +829,18 @@ func (r *refreshRepo) RevokeAllForUser(ctx context.Context, userID uuid.UUID) er
 
 type resetRepo struct{ q gen.Querier }
 
+func toPasswordReset(pr gen.PasswordReset) domain.PasswordReset {
+	return domain.PasswordReset{
+		ID:        pr.ID,
+		UserID:    pr.UserID,
+		OTPHash:   pr.OtpHash,
+		ExpiresAt: pr.ExpiresAt,
+		UsedAt:    ptrTime(pr.UsedAt),
+		Attempts:  int(pr.Attempts),
+		CreatedAt: pr.CreatedAt,
+	}
+}
+
 func (r *resetRepo) CreateOTP(ctx context.Context, userID uuid.UUID, otpHash string, exp time.Time) (domain.PasswordReset, error) {
 	pr, err := r.q.CreatePasswordResetOTP(ctx, gen.CreatePasswordResetOTPParams{
 		UserID:    userID,
@@ -396,13 +864,7 @@ func (r *resetRepo) CreateOTP(ctx context.Context, userID uuid.UUID, otpHash str
 		Time("expires_at", pr.ExpiresAt).
 		Msg("password reset created")
 
-	return domain.PasswordReset{
-		ID:        pr.ID,
-		UserID:    pr.UserID,
-		OTPHash:   pr.OtpHash,
-		ExpiresAt: pr.ExpiresAt,
-		UsedAt:    ptrTime(pr.UsedAt),
-	}, nil
+	return toPasswordReset(pr), nil
 }
 
 func (r *resetRepo) FindValidByUser(ctx context.Context, userID uuid.UUID, _ time.Time) (domain.PasswordReset, error) {
@@ -430,28 +892,1217 @@ func (r *resetRepo) FindValidByUser(ctx context.Context, userID uuid.UUID, _ tim
 		Time("expires_at", pr.ExpiresAt).
 		Msg("valid password reset fetched")
 
-	return domain.PasswordReset{
-		ID:        pr.ID,
-		UserID:    pr.UserID,
-		OTPHash:   pr.OtpHash,
-		ExpiresAt: pr.ExpiresAt,
-		UsedAt:    ptrTime(pr.UsedAt),
-	}, nil
+	return toPasswordReset(pr), nil
 }
 
-func (r *resetRepo) MarkUsed(ctx context.Context, id uuid.UUID) error {
-	if err := r.q.MarkPasswordResetUsed(ctx, id); err != nil {
-		log.Error().
-			Err(err).
-			Str("operation", "reset.MarkUsed").
-			Str("reset_id", id.String()).
-			Msg("failed to mark reset as used")
-		return err
+func (r *resetRepo) LatestByUser(ctx context.Context, userID uuid.UUID) (domain.PasswordReset, error) {
+	pr, err := r.q.GetLatestPasswordResetByUser(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error().
+				Err(err).
+				Str("operation", "reset.LatestByUser").
+				Str("user_id", userID.String()).
+				Msg("failed to get latest password reset")
+		}
+		return domain.PasswordReset{}, mapNotFound(err)
+	}
+
+	log.Debug().
+		Str("operation", "reset.LatestByUser").
+		Str("reset_id", pr.ID.String()).
+		Str("user_id", pr.UserID.String()).
+		Time("created_at", pr.CreatedAt).
+		Msg("latest password reset fetched")
+	return toPasswordReset(pr), nil
+}
+
+func (r *resetRepo) ClaimAttempt(ctx context.Context, id uuid.UUID, maxAttempts int) (domain.PasswordReset, error) {
+	pr, err := r.q.ClaimPasswordResetAttempt(ctx, gen.ClaimPasswordResetAttemptParams{
+		ID:          id,
+		MaxAttempts: int32(maxAttempts),
+	})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error().
+				Err(err).
+				Str("operation", "reset.ClaimAttempt").
+				Str("reset_id", id.String()).
+				Msg("failed to claim reset attempt")
+		}
+		return domain.PasswordReset{}, mapNotFound(err)
+	}
+
+	log.Debug().
+		Str("operation", "reset.ClaimAttempt").
+		Str("reset_id", id.String()).
+		Int32("attempts", pr.Attempts).
+		Msg("reset attempt claimed")
+	return toPasswordReset(pr), nil
+}
+
+func (r *resetRepo) MarkUsed(ctx context.Context, id uuid.UUID) (bool, error) {
+	n, err := r.q.MarkPasswordResetUsed(ctx, id)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "reset.MarkUsed").
+			Str("reset_id", id.String()).
+			Msg("failed to mark reset as used")
+		return false, err
 	}
 
 	log.Debug().
 		Str("operation", "reset.MarkUsed").
 		Str("reset_id", id.String()).
+		Bool("updated", n > 0).
 		Msg("password reset marked as used")
+	return n > 0, nil
+}
+
+/* ================= magic_links ================= */
+
+type magicLinkRepo struct{ q gen.Querier }
+
+func toMagicLink(m gen.MagicLink) domain.MagicLink {
+	return domain.MagicLink{
+		ID:         m.ID,
+		UserID:     m.UserID,
+		CreatedAt:  m.CreatedAt,
+		ExpiresAt:  m.ExpiresAt,
+		ConsumedAt: ptrTime(m.ConsumedAt),
+	}
+}
+
+func (r *magicLinkRepo) Create(ctx context.Context, id, userID uuid.UUID, exp time.Time) (domain.MagicLink, error) {
+	m, err := r.q.CreateMagicLink(ctx, gen.CreateMagicLinkParams{
+		ID:        id,
+		UserID:    userID,
+		ExpiresAt: exp,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "magicLinks.Create").
+			Str("user_id", userID.String()).
+			Msg("failed to create magic link")
+		return domain.MagicLink{}, err
+	}
+	return toMagicLink(m), nil
+}
+
+func (r *magicLinkRepo) Consume(ctx context.Context, id uuid.UUID) (domain.MagicLink, error) {
+	m, err := r.q.ConsumeMagicLink(ctx, id)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error().
+				Err(err).
+				Str("operation", "magicLinks.Consume").
+				Str("link_id", id.String()).
+				Msg("failed to consume magic link")
+		}
+		return domain.MagicLink{}, mapNotFound(err)
+	}
+	return toMagicLink(m), nil
+}
+
+func (r *magicLinkRepo) LatestByUser(ctx context.Context, userID uuid.UUID) (domain.MagicLink, error) {
+	m, err := r.q.GetLatestMagicLinkByUser(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error().
+				Err(err).
+				Str("operation", "magicLinks.LatestByUser").
+				Str("user_id", userID.String()).
+				Msg("failed to get latest magic link")
+		}
+		return domain.MagicLink{}, mapNotFound(err)
+	}
+	return toMagicLink(m), nil
+}
+
+func (r *magicLinkRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	n, err := r.q.DeleteExpiredMagicLinks(ctx)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "magicLinks.DeleteExpired").
+			Msg("failed to delete expired magic links")
+		return 0, err
+	}
+	return n, nil
+}
+
+/* ================= login_failures ================= */
+
+type loginThrottleRepo struct{ q gen.Querier }
+
+func (r *loginThrottleRepo) Get(ctx context.Context, keys []string) ([]domain.LoginFailures, error) {
+	rows, err := r.q.GetLoginFailures(ctx, keys)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "loginThrottle.Get").
+			Strs("keys", keys).
+			Msg("failed to get login failures")
+		return nil, err
+	}
+
+	out := make([]domain.LoginFailures, 0, len(rows))
+	for _, lf := range rows {
+		out = append(out, domain.LoginFailures{
+			Key:          lf.Key,
+			Failures:     int(lf.Failures),
+			LastFailedAt: lf.LastFailedAt,
+			LockedUntil:  ptrTime(lf.LockedUntil),
+		})
+	}
+	return out, nil
+}
+
+func (r *loginThrottleRepo) RecordFailure(ctx context.Context, key string, now, resetBefore time.Time) (int, error) {
+	n, err := r.q.RecordLoginFailure(ctx, gen.RecordLoginFailureParams{
+		Key:         key,
+		Now:         now,
+		ResetBefore: resetBefore,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "loginThrottle.RecordFailure").
+			Str("key", key).
+			Msg("failed to record login failure")
+		return 0, err
+	}
+
+	log.Debug().
+		Str("operation", "loginThrottle.RecordFailure").
+		Str("key", key).
+		Int32("failures", n).
+		Msg("login failure recorded")
+	return int(n), nil
+}
+
+func (r *loginThrottleRepo) SetLockedUntil(ctx context.Context, key string, until time.Time) error {
+	if err := r.q.SetLoginLockedUntil(ctx, gen.SetLoginLockedUntilParams{
+		Key:         key,
+		LockedUntil: sql.NullTime{Time: until, Valid: true},
+	}); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "loginThrottle.SetLockedUntil").
+			Str("key", key).
+			Msg("failed to set locked_until")
+		return err
+	}
+
+	log.Debug().
+		Str("operation", "loginThrottle.SetLockedUntil").
+		Str("key", key).
+		Time("locked_until", until).
+		Msg("login locked")
+	return nil
+}
+
+func (r *loginThrottleRepo) Clear(ctx context.Context, key string) error {
+	if err := r.q.ClearLoginFailures(ctx, key); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "loginThrottle.Clear").
+			Str("key", key).
+			Msg("failed to clear login failures")
+		return err
+	}
+
+	log.Debug().
+		Str("operation", "loginThrottle.Clear").
+		Str("key", key).
+		Msg("login failures cleared")
+	return nil
+}
+
+func (r *loginThrottleRepo) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	n, err := r.q.DeleteStaleLoginFailures(ctx, before)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "loginThrottle.DeleteStale").
+			Msg("failed to delete stale login failures")
+		return 0, err
+	}
+	return n, nil
+}
+
+/* ================= user_mfa ================= */
+
+type mfaRepo struct{ q gen.Querier }
+
+func (r *mfaRepo) Get(ctx context.Context, userID uuid.UUID) (domain.UserMFA, error) {
+	m, err := r.q.GetUserMFA(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error().
+				Err(err).
+				Str("operation", "mfa.Get").
+				Str("user_id", userID.String()).
+				Msg("failed to get user mfa")
+		}
+		return domain.UserMFA{}, mapNotFound(err)
+	}
+
+	return domain.UserMFA{
+		UserID:       m.UserID,
+		TOTPSecret:   m.TotpSecret,
+		ConfirmedAt:  ptrTime(m.ConfirmedAt),
+		LastUsedStep: m.LastUsedStep,
+		CreatedAt:    m.CreatedAt,
+	}, nil
+}
+
+func (r *mfaRepo) StartEnrollment(ctx context.Context, userID uuid.UUID, secret string) error {
+	n, err := r.q.StartMFAEnrollment(ctx, gen.StartMFAEnrollmentParams{
+		UserID:     userID,
+		TotpSecret: secret,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "mfa.StartEnrollment").
+			Str("user_id", userID.String()).
+			Msg("failed to start mfa enrollment")
+		return err
+	}
+	if n == 0 {
+		return domain.ErrMFAEnabled
+	}
+
+	log.Debug().
+		Str("operation", "mfa.StartEnrollment").
+		Str("user_id", userID.String()).
+		Msg("mfa enrollment started")
+	return nil
+}
+
+func (r *mfaRepo) Confirm(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	n, err := r.q.ConfirmUserMFA(ctx, gen.ConfirmUserMFAParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "mfa.Confirm").
+			Str("user_id", userID.String()).
+			Msg("failed to confirm mfa")
+		return false, err
+	}
+
+	log.Debug().
+		Str("operation", "mfa.Confirm").
+		Str("user_id", userID.String()).
+		Int64("rows", n).
+		Msg("mfa confirmation applied")
+	return n > 0, nil
+}
+
+func (r *mfaRepo) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	n, err := r.q.UseTOTPStep(ctx, gen.UseTOTPStepParams{
+		UserID:       userID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "mfa.UseStep").
+			Str("user_id", userID.String()).
+			Msg("failed to use totp step")
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *mfaRepo) Delete(ctx context.Context, userID uuid.UUID) error {
+	if err := r.q.DeleteUserMFA(ctx, userID); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "mfa.Delete").
+			Str("user_id", userID.String()).
+			Msg("failed to delete user mfa")
+		return err
+	}
+
+	log.Debug().
+		Str("operation", "mfa.Delete").
+		Str("user_id", userID.String()).
+		Msg("user mfa deleted")
+	return nil
+}
+
+func (r *mfaRepo) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes [][]byte) error {
+	if err := r.q.ReplaceRecoveryCodes(ctx, gen.ReplaceRecoveryCodesParams{
+		UserID:     userID,
+		CodeHashes: hashes,
+	}); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "mfa.ReplaceRecoveryCodes").
+			Str("user_id", userID.String()).
+			Msg("failed to replace recovery codes")
+		return err
+	}
+
+	log.Debug().
+		Str("operation", "mfa.ReplaceRecoveryCodes").
+		Str("user_id", userID.String()).
+		Int("count", len(hashes)).
+		Msg("recovery codes replaced")
+	return nil
+}
+
+func (r *mfaRepo) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash []byte) (bool, error) {
+	n, err := r.q.UseRecoveryCode(ctx, gen.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: hash,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "mfa.UseRecoveryCode").
+			Str("user_id", userID.String()).
+			Msg("failed to use recovery code")
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *mfaRepo) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	n, err := r.q.CountUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "mfa.CountRecoveryCodes").
+			Str("user_id", userID.String()).
+			Msg("failed to count recovery codes")
+		return 0, err
+	}
+	return int(n), nil
+}
+
+/* ================= user_identities ================= */
+
+type identityRepo struct{ q gen.Querier }
+
+func toIdentity(i gen.UserIdentity) domain.Identity {
+	return domain.Identity{
+		ID:          i.ID,
+		UserID:      i.UserID,
+		Provider:    i.Provider,
+		Subject:     i.Subject,
+		Email:       optString(i.Email),
+		CreatedAt:   i.CreatedAt,
+		LastLoginAt: ptrTime(i.LastLoginAt),
+	}
+}
+
+func (r *identityRepo) ByProviderSubject(ctx context.Context, provider, subject string) (domain.Identity, error) {
+	i, err := r.q.GetIdentity(ctx, gen.GetIdentityParams{
+		Provider: provider,
+		Subject:  subject,
+	})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error().
+				Err(err).
+				Str("operation", "identities.ByProviderSubject").
+				Str("provider", provider).
+				Msg("failed to get identity")
+		}
+		return domain.Identity{}, mapNotFound(err)
+	}
+	return toIdentity(i), nil
+}
+
+func (r *identityRepo) Create(ctx context.Context, in domain.Identity) (domain.Identity, error) {
+	i, err := r.q.CreateIdentity(ctx, gen.CreateIdentityParams{
+		UserID:   in.UserID,
+		Provider: in.Provider,
+		Subject:  in.Subject,
+		Email:    nullString(in.Email),
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.Identity{}, domain.ErrAlreadyExists
+		}
+		log.Error().
+			Err(err).
+			Str("operation", "identities.Create").
+			Str("user_id", in.UserID.String()).
+			Str("provider", in.Provider).
+			Msg("failed to create identity")
+		return domain.Identity{}, err
+	}
+
+	log.Debug().
+		Str("operation", "identities.Create").
+		Str("identity_id", i.ID.String()).
+		Str("user_id", i.UserID.String()).
+		Str("provider", i.Provider).
+		Msg("identity linked")
+	return toIdentity(i), nil
+}
+
+func (r *identityRepo) Touch(ctx context.Context, id uuid.UUID, email *string) error {
+	if err := r.q.TouchIdentity(ctx, gen.TouchIdentityParams{
+		ID:    id,
+		Email: nullString(email),
+	}); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "identities.Touch").
+			Str("identity_id", id.String()).
+			Msg("failed to touch identity")
+		return err
+	}
+	return nil
+}
+
+func (r *identityRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Identity, error) {
+	rows, err := r.q.ListIdentitiesByUser(ctx, userID)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "identities.ListByUser").
+			Str("user_id", userID.String()).
+			Msg("failed to list identities")
+		return nil, err
+	}
+	out := make([]domain.Identity, 0, len(rows))
+	for _, i := range rows {
+		out = append(out, toIdentity(i))
+	}
+	return out, nil
+}
+
+/* ================= oidc_auth_requests ================= */
+
+type oidcRequestRepo struct{ q gen.Querier }
+
+func toOIDCRequest(r gen.OidcAuthRequest) domain.OIDCRequest {
+	return domain.OIDCRequest{
+		State:        r.State,
+		Provider:     r.Provider,
+		Nonce:        r.Nonce,
+		CodeVerifier: r.CodeVerifier,
+		ReturnTo:     r.ReturnTo,
+		UserID:       ptrUUID(r.UserID),
+		ExpiresAt:    r.ExpiresAt,
+	}
+}
+
+func (r *oidcRequestRepo) Create(ctx context.Context, in domain.OIDCRequest) error {
+	if err := r.q.CreateOIDCRequest(ctx, gen.CreateOIDCRequestParams{
+		State:        in.State,
+		Provider:     in.Provider,
+		Nonce:        in.Nonce,
+		CodeVerifier: in.CodeVerifier,
+		ReturnTo:     in.ReturnTo,
+		ExpiresAt:    in.ExpiresAt,
+	}); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "oidcRequests.Create").
+			Str("provider", in.Provider).
+			Msg("failed to create oidc request")
+		return err
+	}
 	return nil
 }
+
+func (r *oidcRequestRepo) ConsumeState(ctx context.Context, state, provider string) (domain.OIDCRequest, error) {
+	req, err := r.q.ConsumeOIDCState(ctx, gen.ConsumeOIDCStateParams{
+		State:    state,
+		Provider: provider,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn().
+				Str("operation", "oidcRequests.ConsumeState").
+				Str("provider", provider).
+				Msg("unknown, used or expired oidc state")
+		} else {
+			log.Error().
+				Err(err).
+				Str("operation", "oidcRequests.ConsumeState").
+				Str("provider", provider).
+				Msg("failed to consume oidc state")
+		}
+		return domain.OIDCRequest{}, mapNotFound(err)
+	}
+	return toOIDCRequest(req), nil
+}
+
+func (r *oidcRequestRepo) SetLoginCode(ctx context.Context, state string, userID uuid.UUID, codeHash []byte, exp time.Time) error {
+	if err := r.q.SetOIDCLoginCode(ctx, gen.SetOIDCLoginCodeParams{
+		State:         state,
+		UserID:        uuid.NullUUID{UUID: userID, Valid: true},
+		LoginCodeHash: codeHash,
+		ExpiresAt:     exp,
+	}); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "oidcRequests.SetLoginCode").
+			Str("user_id", userID.String()).
+			Msg("failed to set oidc login code")
+		return err
+	}
+	return nil
+}
+
+func (r *oidcRequestRepo) ConsumeLoginCode(ctx context.Context, codeHash []byte) (domain.OIDCRequest, error) {
+	req, err := r.q.ConsumeOIDCLoginCode(ctx, codeHash)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error().
+				Err(err).
+				Str("operation", "oidcRequests.ConsumeLoginCode").
+				Msg("failed to consume oidc login code")
+		}
+		return domain.OIDCRequest{}, mapNotFound(err)
+	}
+	return toOIDCRequest(req), nil
+}
+
+func (r *oidcRequestRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	n, err := r.q.DeleteExpiredOIDCRequests(ctx)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "oidcRequests.DeleteExpired").
+			Msg("failed to delete expired oidc requests")
+		return 0, err
+	}
+	return n, nil
+}
+
+/* ================= revoked_tokens ================= */
+
+type revokedTokenRepo struct{ q gen.Querier }
+
+func (r *revokedTokenRepo) Revoke(ctx context.Context, jti string, userID *uuid.UUID, expiresAt time.Time) error {
+	if err := r.q.RevokeToken(ctx, gen.RevokeTokenParams{
+		Jti:       jti,
+		UserID:    nullUUID(userID),
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "revokedTokens.Revoke").
+			Str("jti", jti).
+			Msg("failed to revoke access token")
+		return err
+	}
+	return nil
+}
+
+func (r *revokedTokenRepo) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	revoked, err := r.q.IsTokenRevoked(ctx, jti)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "revokedTokens.IsRevoked").
+			Msg("failed to check access token revocation")
+		return false, err
+	}
+	return revoked, nil
+}
+
+func (r *revokedTokenRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	n, err := r.q.DeleteExpiredRevokedTokens(ctx)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "revokedTokens.DeleteExpired").
+			Msg("failed to delete expired revoked tokens")
+		return 0, err
+	}
+	return n, nil
+}
+
+/* ================= oauth_clients ================= */
+
+type oauthClientRepo struct{ q gen.Querier }
+
+func toOAuthClient(c gen.OauthClient) domain.OAuthClient {
+	return domain.OAuthClient{
+		ID:           c.ID,
+		Name:         c.Name,
+		SecretHash:   c.SecretHash,
+		Scopes:       c.Scopes,
+		RedirectURIs: c.RedirectUris,
+		FirstParty:   c.FirstParty,
+		CreatedAt:    c.CreatedAt,
+		DisabledAt:   ptrTime(c.DisabledAt),
+	}
+}
+
+func (r *oauthClientRepo) Create(ctx context.Context, in domain.OAuthClient) (domain.OAuthClient, error) {
+	c, err := r.q.CreateOAuthClient(ctx, gen.CreateOAuthClientParams{
+		ID:           in.ID,
+		Name:         in.Name,
+		SecretHash:   in.SecretHash,
+		Scopes:       in.Scopes,
+		RedirectUris: in.RedirectURIs,
+		FirstParty:   in.FirstParty,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.OAuthClient{}, domain.ErrAlreadyExists
+		}
+		log.Error().
+			Err(err).
+			Str("operation", "oauthClients.Create").
+			Str("client_id", in.ID).
+			Msg("failed to create oauth client")
+		return domain.OAuthClient{}, err
+	}
+
+	log.Info().
+		Str("operation", "oauthClients.Create").
+		Str("client_id", c.ID).
+		Str("name", c.Name).
+		Msg("oauth client created")
+	return toOAuthClient(c), nil
+}
+
+func (r *oauthClientRepo) ByID(ctx context.Context, id string) (domain.OAuthClient, error) {
+	c, err := r.q.GetOAuthClient(ctx, id)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error().
+				Err(err).
+				Str("operation", "oauthClients.ByID").
+				Str("client_id", id).
+				Msg("failed to get oauth client")
+		}
+		return domain.OAuthClient{}, mapNotFound(err)
+	}
+	return toOAuthClient(c), nil
+}
+
+func (r *oauthClientRepo) List(ctx context.Context) ([]domain.OAuthClient, error) {
+	rows, err := r.q.ListOAuthClients(ctx)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "oauthClients.List").
+			Msg("failed to list oauth clients")
+		return nil, err
+	}
+	out := make([]domain.OAuthClient, 0, len(rows))
+	for _, c := range rows {
+		out = append(out, toOAuthClient(c))
+	}
+	return out, nil
+}
+
+func (r *oauthClientRepo) Disable(ctx context.Context, id string) (bool, error) {
+	n, err := r.q.DisableOAuthClient(ctx, id)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "oauthClients.Disable").
+			Str("client_id", id).
+			Msg("failed to disable oauth client")
+		return false, err
+	}
+	return n > 0, nil
+}
+
+/* ================= oauth_authorization_codes ================= */
+
+type authCodeRepo struct{ q gen.Querier }
+
+func toAuthorizationCode(c gen.OauthAuthorizationCode) domain.AuthorizationCode {
+	return domain.AuthorizationCode{
+		CodeHash:      c.CodeHash,
+		ClientID:      c.ClientID,
+		UserID:        c.UserID,
+		RedirectURI:   c.RedirectUri,
+		Scopes:        c.Scopes,
+		CodeChallenge: c.CodeChallenge,
+		FamilyID:      ptrUUID(c.FamilyID),
+		CreatedAt:     c.CreatedAt,
+		ExpiresAt:     c.ExpiresAt,
+		ConsumedAt:    ptrTime(c.ConsumedAt),
+	}
+}
+
+func (r *authCodeRepo) Create(ctx context.Context, in domain.AuthorizationCode) error {
+	err := r.q.CreateAuthorizationCode(ctx, gen.CreateAuthorizationCodeParams{
+		CodeHash:      in.CodeHash,
+		ClientID:      in.ClientID,
+		UserID:        in.UserID,
+		RedirectUri:   in.RedirectURI,
+		Scopes:        in.Scopes,
+		CodeChallenge: in.CodeChallenge,
+		ExpiresAt:     in.ExpiresAt,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "authCodes.Create").
+			Str("client_id", in.ClientID).
+			Str("user_id", in.UserID.String()).
+			Msg("failed to create authorization code")
+		return err
+	}
+	return nil
+}
+
+func (r *authCodeRepo) Consume(ctx context.Context, codeHash []byte) (domain.AuthorizationCode, error) {
+	c, err := r.q.ConsumeAuthorizationCode(ctx, codeHash)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error().
+				Err(err).
+				Str("operation", "authCodes.Consume").
+				Msg("failed to consume authorization code")
+		}
+		return domain.AuthorizationCode{}, mapNotFound(err)
+	}
+	return toAuthorizationCode(c), nil
+}
+
+func (r *authCodeRepo) ByHash(ctx context.Context, codeHash []byte) (domain.AuthorizationCode, error) {
+	c, err := r.q.GetAuthorizationCode(ctx, codeHash)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error().
+				Err(err).
+				Str("operation", "authCodes.ByHash").
+				Msg("failed to get authorization code")
+		}
+		return domain.AuthorizationCode{}, mapNotFound(err)
+	}
+	return toAuthorizationCode(c), nil
+}
+
+func (r *authCodeRepo) SetFamily(ctx context.Context, codeHash []byte, familyID uuid.UUID) error {
+	err := r.q.SetAuthorizationCodeFamily(ctx, gen.SetAuthorizationCodeFamilyParams{
+		CodeHash: codeHash,
+		FamilyID: nullUUID(&familyID),
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "authCodes.SetFamily").
+			Str("family_id", familyID.String()).
+			Msg("failed to link authorization code to session")
+		return err
+	}
+	return nil
+}
+
+func (r *authCodeRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	n, err := r.q.DeleteExpiredAuthorizationCodes(ctx)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "authCodes.DeleteExpired").
+			Msg("failed to delete expired authorization codes")
+		return 0, err
+	}
+	return n, nil
+}
+
+/* ================= oauth_consents ================= */
+
+type consentRepo struct{ q gen.Querier }
+
+func (r *consentRepo) Grant(ctx context.Context, userID uuid.UUID, clientID string, scopes []string) error {
+	err := r.q.UpsertOAuthConsent(ctx, gen.UpsertOAuthConsentParams{
+		UserID:   userID,
+		ClientID: clientID,
+		Scopes:   scopes,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "consents.Grant").
+			Str("user_id", userID.String()).
+			Str("client_id", clientID).
+			Msg("failed to save oauth consent")
+		return err
+	}
+
+	log.Info().
+		Str("operation", "consents.Grant").
+		Str("user_id", userID.String()).
+		Str("client_id", clientID).
+		Strs("scopes", scopes).
+		Msg("oauth consent granted")
+	return nil
+}
+
+func (r *consentRepo) Get(ctx context.Context, userID uuid.UUID, clientID string) (domain.OAuthConsent, error) {
+	c, err := r.q.GetOAuthConsent(ctx, gen.GetOAuthConsentParams{UserID: userID, ClientID: clientID})
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error().
+				Err(err).
+				Str("operation", "consents.Get").
+				Str("user_id", userID.String()).
+				Str("client_id", clientID).
+				Msg("failed to get oauth consent")
+		}
+		return domain.OAuthConsent{}, mapNotFound(err)
+	}
+	return domain.OAuthConsent{
+		UserID:    c.UserID,
+		ClientID:  c.ClientID,
+		Scopes:    c.Scopes,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}, nil
+}
+
+func (r *consentRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.OAuthConsent, error) {
+	rows, err := r.q.ListOAuthConsentsForUser(ctx, userID)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "consents.ListByUser").
+			Str("user_id", userID.String()).
+			Msg("failed to list oauth consents")
+		return nil, err
+	}
+	out := make([]domain.OAuthConsent, 0, len(rows))
+	for _, c := range rows {
+		out = append(out, domain.OAuthConsent{
+			UserID:     userID,
+			ClientID:   c.ClientID,
+			ClientName: c.ClientName,
+			Scopes:     c.Scopes,
+			CreatedAt:  c.CreatedAt,
+			UpdatedAt:  c.UpdatedAt,
+		})
+	}
+	return out, nil
+}
+
+func (r *consentRepo) Delete(ctx context.Context, userID uuid.UUID, clientID string) (bool, error) {
+	n, err := r.q.DeleteOAuthConsent(ctx, gen.DeleteOAuthConsentParams{UserID: userID, ClientID: clientID})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "consents.Delete").
+			Str("user_id", userID.String()).
+			Str("client_id", clientID).
+			Msg("failed to delete oauth consent")
+		return false, err
+	}
+	return n > 0, nil
+}
+
+/* ================= personal_access_tokens ================= */
+
+type personalTokenRepo struct{ q gen.Querier }
+
+func toPersonalToken(t gen.PersonalAccessToken) domain.PersonalToken {
+	return domain.PersonalToken{
+		ID:         t.ID,
+		UserID:     t.UserID,
+		Name:       t.Name,
+		TokenHash:  t.TokenHash,
+		Scopes:     t.Scopes,
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: ptrTime(t.LastUsedAt),
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+func (r *personalTokenRepo) Create(ctx context.Context, in domain.PersonalToken) (domain.PersonalToken, error) {
+	t, err := r.q.CreatePersonalToken(ctx, gen.CreatePersonalTokenParams{
+		UserID:    in.UserID,
+		Name:      in.Name,
+		TokenHash: in.TokenHash,
+		Scopes:    in.Scopes,
+		ExpiresAt: in.ExpiresAt,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "personalTokens.Create").
+			Str("user_id", in.UserID.String()).
+			Msg("failed to create personal access token")
+		return domain.PersonalToken{}, err
+	}
+
+	log.Info().
+		Str("operation", "personalTokens.Create").
+		Str("user_id", t.UserID.String()).
+		Str("token_id", t.ID.String()).
+		Msg("personal access token created")
+	return toPersonalToken(t), nil
+}
+
+func (r *personalTokenRepo) ByHashActive(ctx context.Context, tokenHash []byte) (domain.PersonalToken, error) {
+	t, err := r.q.GetActivePersonalToken(ctx, tokenHash)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error().
+				Err(err).
+				Str("operation", "personalTokens.ByHashActive").
+				Msg("failed to get personal access token")
+		}
+		return domain.PersonalToken{}, mapNotFound(err)
+	}
+	return toPersonalToken(t), nil
+}
+
+func (r *personalTokenRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.PersonalToken, error) {
+	rows, err := r.q.ListPersonalTokens(ctx, userID)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "personalTokens.ListByUser").
+			Str("user_id", userID.String()).
+			Msg("failed to list personal access tokens")
+		return nil, err
+	}
+	out := make([]domain.PersonalToken, 0, len(rows))
+	for _, t := range rows {
+		out = append(out, toPersonalToken(t))
+	}
+	return out, nil
+}
+
+func (r *personalTokenRepo) Revoke(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	n, err := r.q.RevokePersonalToken(ctx, gen.RevokePersonalTokenParams{ID: id, UserID: userID})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "personalTokens.Revoke").
+			Str("token_id", id.String()).
+			Msg("failed to revoke personal access token")
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *personalTokenRepo) Touch(ctx context.Context, id uuid.UUID) error {
+	if err := r.q.TouchPersonalToken(ctx, id); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "personalTokens.Touch").
+			Str("token_id", id.String()).
+			Msg("failed to update personal access token last_used_at")
+		return err
+	}
+	return nil
+}
+
+/* ================= auth_events ================= */
+
+type eventRepo struct{ q gen.Querier }
+
+func (r *eventRepo) Record(ctx context.Context, e domain.AuthEvent) error {
+	details := e.Details
+	if details == nil {
+		details = map[string]any{}
+	}
+	raw, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	if err := r.q.CreateAuthEvent(ctx, gen.CreateAuthEventParams{
+		UserID:    nullUUID(e.UserID),
+		ActorID:   nullUUID(e.ActorID),
+		Type:      e.Type,
+		Ip:        inet(e.IP),
+		UserAgent: nullString(e.UserAgent),
+		Details:   raw,
+	}); err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "events.Record").
+			Str("type", e.Type).
+			Msg("failed to record auth event")
+		return err
+	}
+
+	log.Debug().
+		Str("operation", "events.Record").
+		Str("type", e.Type).
+		Msg("auth event recorded")
+	return nil
+}
+
+func (r *eventRepo) ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]domain.AuthEvent, error) {
+	rows, err := r.q.ListUserAuthEvents(ctx, gen.ListUserAuthEventsParams{
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "events.ListByUser").
+			Str("user_id", userID.String()).
+			Msg("failed to list auth events")
+		return nil, err
+	}
+	return toAuthEvents(rows), nil
+}
+
+func (r *eventRepo) Search(ctx context.Context, f domain.EventFilter) ([]domain.AuthEvent, error) {
+	p := gen.SearchAuthEventsParams{
+		UserID:    nullUUID(f.UserID),
+		ActorID:   nullUUID(f.ActorID),
+		Types:     f.Types,
+		PageLimit: int32(f.Limit),
+	}
+	if p.Types == nil {
+		// NULL-массив отфильтровал бы всё
+		p.Types = []string{}
+	}
+	if f.IP != "" {
+		ip, err := inetOrCIDR(f.IP)
+		if err != nil {
+			return nil, domain.ErrInvalidFilter
+		}
+		p.Ip = ip
+	}
+	if f.Since != nil {
+		p.Since = sql.NullTime{Time: *f.Since, Valid: true}
+	}
+	if f.Until != nil {
+		p.Until = sql.NullTime{Time: *f.Until, Valid: true}
+	}
+	if f.BeforeID > 0 {
+		p.BeforeID = sql.NullInt64{Int64: f.BeforeID, Valid: true}
+	}
+
+	rows, err := r.q.SearchAuthEvents(ctx, p)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "events.Search").
+			Msg("failed to search auth events")
+		return nil, err
+	}
+	return toAuthEvents(rows), nil
+}
+
+func toAuthEvents(rows []gen.AuthEvent) []domain.AuthEvent {
+	out := make([]domain.AuthEvent, 0, len(rows))
+	for _, e := range rows {
+		var details map[string]any
+		if len(e.Details) > 0 {
+			_ = json.Unmarshal(e.Details, &details)
+		}
+		out = append(out, domain.AuthEvent{
+			ID:        e.ID,
+			UserID:    ptrUUID(e.UserID),
+			ActorID:   ptrUUID(e.ActorID),
+			Type:      e.Type,
+			IP:        optString(e.Ip),
+			UserAgent: optString(e.UserAgent),
+			Details:   details,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	return out
+}
+
+// inetOrCIDR принимает одиночный адрес или подсеть (10.0.0.0/8).
+func inetOrCIDR(s string) (pqtype.Inet, error) {
+	if !strings.Contains(s, "/") {
+		ip := inet(&s)
+		if !ip.Valid {
+			return pqtype.Inet{}, fmt.Errorf("invalid ip %q", s)
+		}
+		return ip, nil
+	}
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return pqtype.Inet{}, err
+	}
+	if v4 := n.IP.To4(); v4 != nil {
+		n.IP = v4
+	}
+	return pqtype.Inet{IPNet: *n, Valid: true}, nil
+}
+
+/* ====================== roles ====================== */
+
+type roleRepo struct{ q gen.Querier }
+
+func (r *roleRepo) List(ctx context.Context) ([]domain.Role, error) {
+	rows, err := r.q.ListRoles(ctx)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "roles.List").
+			Msg("failed to list roles")
+		return nil, err
+	}
+
+	out := make([]domain.Role, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, domain.Role{
+			Name:        row.Name,
+			Description: row.Description,
+			CreatedAt:   row.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+func (r *roleRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	roles, err := r.q.ListUserRoles(ctx, userID)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "roles.ListByUser").
+			Str("user_id", userID.String()).
+			Msg("failed to list user roles")
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *roleRepo) Grant(ctx context.Context, userID uuid.UUID, role string, grantedBy *uuid.UUID) (bool, error) {
+	n, err := r.q.GrantRole(ctx, gen.GrantRoleParams{
+		UserID:    userID,
+		Role:      role,
+		GrantedBy: nullUUID(grantedBy),
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "roles.Grant").
+			Str("user_id", userID.String()).
+			Str("role", role).
+			Msg("failed to grant role")
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *roleRepo) Revoke(ctx context.Context, userID uuid.UUID, role string) (bool, error) {
+	n, err := r.q.RevokeRole(ctx, gen.RevokeRoleParams{
+		UserID: userID,
+		Role:   role,
+	})
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("operation", "roles.Revoke").
+			Str("user_id", userID.String()).
+			Str("role", role).
+			Msg("failed to revoke role")
+		return false, err
+	}
+	return n > 0, nil
+}
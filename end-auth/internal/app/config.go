@@ -3,6 +3,8 @@ package app
 import (
 	"strings"
 
+	"auth/internal/adapter/out/mail"
+	"auth/internal/adapter/out/oidc"
 	"auth/internal/service"
 
 	"auth/internal/utils/env"
@@ -25,10 +27,14 @@ type Config struct {
 	DB     DBConfig       `mapstructure:"db"`
 	Logger LoggerConfig `mapstructure:"logger"`
 	Svc    service.Config `mapstructure:"svc"`
+	Mail   mail.Config    `mapstructure:"mail"`
+	OIDC   oidc.Config    `mapstructure:"oidc"`
 }
 
 type HTTPConfig struct {
 	Addr string `mapstructure:"addr" default:":8081"`
+	// TrustedProxies — адреса/CIDR прокси, чьим X-Forwarded-For/X-Real-IP можно верить.
+	TrustedProxies []string `mapstructure:"trustedProxies"`
 }
 
 type DBConfig struct {
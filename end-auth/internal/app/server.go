@@ -3,6 +3,7 @@ package app
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,7 +11,11 @@ import (
 	"time"
 
 	httpin "auth/internal/adapter/in/http"
+	"auth/internal/adapter/out/mail"
+	"auth/internal/adapter/out/oidc"
 	"auth/internal/adapter/out/postgres"
+	"auth/internal/keys"
+	"auth/internal/password"
 	"auth/internal/service"
 
 	_ "github.com/lib/pq"
@@ -20,6 +25,7 @@ import (
 type Server struct {
 	httpSrv *http.Server
 	db      *sql.DB
+	stop    context.CancelFunc
 }
 
 func BuildServer(cfg Config) (*Server, error) {
@@ -37,11 +43,40 @@ func BuildServer(cfg Config) (*Server, error) {
 		}
 	}
 
+	ring, err := buildKeyring(cfg.Svc)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	mailer, err := mail.New(cfg.Mail, cfg.Svc.DevMode)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	providers, err := oidc.New(cfg.OIDC)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	policy, err := password.NewPolicy(cfg.Svc.PasswordPolicy)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("load breached password corpus: %w", err)
+	}
+	if cfg.Svc.DevMode {
+		log.Warn().Msg("svc.devMode is on: password reset codes are returned in API responses")
+	}
+
 	repos := postgres.NewRepositories(db)
-	svc := service.New(repos.User, repos.Refresh, repos.Reset, cfg.Svc)
+	svc := service.New(repos.User, repos.Refresh, repos.Revoked, repos.Reset, repos.Event, repos.Login, repos.MFA, repos.Role, repos.Identity, repos.OIDC, providers, repos.Client, repos.Code, repos.Consent, repos.PAT, repos.Magic, mailer, ring, policy, cfg.Svc)
 
 	h := httpin.NewAuthHandler(svc)
-	engine := httpin.NewGinRouter(h)
+	engine, err := httpin.NewGinRouter(h, cfg.HTTP.TrustedProxies)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
 
 	srv := &http.Server{
 		Addr:              cfg.HTTP.Addr,
@@ -49,7 +84,64 @@ func BuildServer(cfg Config) (*Server, error) {
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
-	return &Server{httpSrv: srv, db: db}, nil
+	bgCtx, stop := context.WithCancel(context.Background())
+	go reloadKeyring(bgCtx, ring, cfg.Svc.Keyring)
+
+	return &Server{httpSrv: srv, db: db, stop: stop}, nil
+}
+
+func buildKeyring(cfg service.Config) (*keys.Ring, error) {
+	if cfg.Keyring.Dir == "" {
+		kid := "ephemeral-" + time.Now().UTC().Format("20060102150405")
+		log.Warn().
+			Str("kid", kid).
+			Msg("keyring dir is not configured, using ephemeral key (tokens will not survive restart)")
+		k, err := keys.Generate(kid, keys.AlgEdDSA)
+		if err != nil {
+			return nil, err
+		}
+		return keys.NewRing(cfg.KeyRetention(), []keys.Entry{{Key: k}})
+	}
+
+	entries, err := keys.LoadDir(cfg.Keyring.Dir)
+	if err != nil {
+		return nil, err
+	}
+	ring, err := keys.NewRing(cfg.KeyRetention(), entries)
+	if err != nil {
+		return nil, fmt.Errorf("keyring %s: %w", cfg.Keyring.Dir, err)
+	}
+	if _, err := ring.Signing(time.Now()); err != nil {
+		return nil, fmt.Errorf("keyring %s: %w", cfg.Keyring.Dir, err)
+	}
+	return ring, nil
+}
+
+// reloadKeyring периодически перечитывает каталог ключей, чтобы новые ключи,
+// добавленные через `authctl keys generate`, подхватывались без рестарта.
+func reloadKeyring(ctx context.Context, ring *keys.Ring, cfg service.KeyringConfig) {
+	if cfg.Dir == "" || cfg.ReloadInterval <= 0 {
+		return
+	}
+	t := time.NewTicker(cfg.ReloadInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			entries, err := keys.LoadDir(cfg.Dir)
+			if err == nil {
+				err = ring.Replace(entries)
+			}
+			if err != nil {
+				log.Error().Err(err).Str("dir", cfg.Dir).Msg("failed to reload keyring, keeping previous keys")
+				continue
+			}
+			log.Debug().Interface("states", ring.States(time.Now())).Msg("keyring reloaded")
+		}
+	}
 }
 
 func (s *Server) Start() error {
@@ -72,6 +164,7 @@ func (s *Server) Start() error {
 	if err := s.httpSrv.Shutdown(ctx); err != nil {
 		log.Error().Err(err).Msg("HTTP server forced to shutdown")
 	}
+	s.stop()
 	_ = s.db.Close()
 
 	log.Info().Msg("Server stopped gracefully")
@@ -3,6 +3,7 @@ package domain
 import (
 	"time"
 
+	"github.com/EnduranNSU/authkit"
 	"github.com/google/uuid"
 )
 
@@ -12,25 +13,298 @@ type User struct {
 	PasswordHash string
 	IsBlocked    bool
 	LastLoginAt  *time.Time
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	// EmailVerifiedAt — когда пользователь подтвердил email; nil, пока не подтвердил.
+	EmailVerifiedAt *time.Time
+	// BlockedReason — причина блокировки, которую указал администратор.
+	BlockedReason *string
+	// BlockedUntil — конец временной блокировки; nil при IsBlocked — бессрочная.
+	BlockedUntil *time.Time
+	// MustResetPassword — администратор потребовал сменить пароль; войти можно только после сброса.
+	MustResetPassword bool
+	// TokensValidAfter — access-токены, выпущенные раньше, отозваны.
+	TokensValidAfter *time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// Blocked сообщает, действует ли блокировка в момент now: временная снимается сама.
+func (u User) Blocked(now time.Time) bool {
+	return u.IsBlocked && (u.BlockedUntil == nil || now.Before(*u.BlockedUntil))
+}
+
+// UserBlock — запись истории блокировок.
+type UserBlock struct {
+	ID      uuid.UUID
+	UserID  uuid.UUID
+	ActorID *uuid.UUID
+	Reason  string
+	// BlockedUntil — nil для бессрочной блокировки.
+	BlockedUntil *time.Time
+	BlockedAt    time.Time
+	// LiftedAt — когда блокировку сняли досрочно; по истечении BlockedUntil не заполняется.
+	LiftedAt   *time.Time
+	LiftedBy   *uuid.UUID
+	LiftReason *string
+}
+
+// EventFilter — поиск по журналу в админке. Пустые поля не фильтруют.
+type EventFilter struct {
+	UserID  *uuid.UUID
+	ActorID *uuid.UUID
+	Types   []string
+	// IP — адрес или подсеть в нотации CIDR.
+	IP    string
+	Since *time.Time
+	Until *time.Time
+	// BeforeID — курсор: id последней записи предыдущей страницы.
+	BeforeID int64
+	Limit    int
 }
 
+// UserFilter — поиск пользователей в админке.
+type UserFilter struct {
+	// Query — подстрока email.
+	Query string
+	// Blocked — фильтр по действующей блокировке (истёкшие временные не считаются).
+	Blocked *bool
+	Limit   int
+	Offset  int
+}
+
+// RefreshSession — одно звено цепочки ротаций. Все сессии, выпущенные из одного
+// логина, делят FamilyID; ParentID указывает на сессию, которую эта заменила.
 type RefreshSession struct {
-	ID        uuid.UUID
-	UserID    uuid.UUID
-	TokenHash []byte
-	UserAgent *string
-	IP        *string
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	FamilyID   uuid.UUID
+	ParentID   *uuid.UUID
+	TokenHash  []byte
+	UserAgent  *string
+	IP         *string
+	DeviceName *string
+	// ClientID — стороннее приложение (OAuth), которому выдана сессия; nil — обычный вход.
+	ClientID *string
+	// Scopes — права сессии приложения; у обычного входа nil.
+	Scopes    []string
 	CreatedAt time.Time
 	ExpiresAt time.Time
 	RevokedAt *time.Time
 }
 
+// DeviceSession — устройство, на котором пользователь сейчас залогинен
+// (активное звено семьи refresh-сессий). ID совпадает с FamilyID.
+type DeviceSession struct {
+	ID           uuid.UUID
+	UserAgent    *string
+	IP           *string
+	DeviceName   *string
+	SignedInAt   time.Time
+	LastActiveAt time.Time
+	ExpiresAt    time.Time
+}
+
 type PasswordReset struct {
 	ID        uuid.UUID
 	UserID    uuid.UUID
 	OTPHash   string
 	ExpiresAt time.Time
 	UsedAt    *time.Time
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// Типы событий безопасности в auth_events.
+const (
+	EventRegistered         = "user.registered"
+	EventLoginSuccess       = "login.success"
+	EventLoginFailure       = "login.failure"
+	EventRefresh            = "refresh"
+	EventRefreshReuse       = "refresh.reuse"
+	EventLogout             = "logout"
+	EventLogoutAll          = "logout.all"
+	EventPasswordResetStart = "password_reset.requested"
+	EventPasswordResetDone  = "password_reset.completed"
+	EventPasswordResetFail  = "password_reset.failed"
+	EventPasswordChange     = "password.changed"
+	EventEmailVerified      = "email.verified"
+	EventLoginLockout       = "login.lockout"
+	EventMFAEnabled         = "mfa.enabled"
+	EventMFADisabled        = "mfa.disabled"
+	EventMFARecovery        = "mfa.recovery_code_used"
+	EventOIDCLinked         = "oidc.linked"
+	EventRoleGranted        = "role.granted"
+	EventRoleRevoked        = "role.revoked"
+	EventTokenRevoked       = "oauth.token_revoked"
+	EventPATCreated         = "pat.created"
+	EventPATRevoked         = "pat.revoked"
+	EventOAuthConsent       = "oauth.consent_granted"
+	EventOAuthAppRevoked    = "oauth.app_revoked"
+	EventOAuthCodeReuse     = "oauth.code_reuse"
+	EventMagicLinkSent      = "login.magic_link_sent"
+
+	EventAdminBlocked        = "admin.user_blocked"
+	EventAdminUnblocked      = "admin.user_unblocked"
+	EventAdminPasswordReset  = "admin.password_reset_forced"
+	EventAdminSessionsRevoke = "admin.sessions_revoked"
+)
+
+// UserMFA — TOTP-аутентификатор пользователя. Пока ConfirmedAt == nil, регистрация
+// не завершена и второй фактор при входе не спрашивается.
+type UserMFA struct {
+	UserID       uuid.UUID
+	TOTPSecret   string
+	ConfirmedAt  *time.Time
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+func (m UserMFA) Enabled() bool { return m.ConfirmedAt != nil }
+
+// Встроенные роли; список хранится в таблице roles, имена общие для всех сервисов (authkit).
+const (
+	RoleAdmin         = authkit.RoleAdmin
+	RoleCoach         = authkit.RoleCoach
+	RoleContentEditor = authkit.RoleContentEditor
+)
+
+type Role struct {
+	Name        string
+	Description string
+	CreatedAt   time.Time
+}
+
+// Identity — внешний аккаунт (subject у OIDC-провайдера), привязанный к пользователю.
+type Identity struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Provider    string
+	Subject     string
+	Email       *string
+	CreatedAt   time.Time
+	LastLoginAt *time.Time
+}
+
+// ExternalIdentity — проверенные claims из ID-токена провайдера.
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// OIDCRequest — незавершённый OIDC-вход. UserID заполняется после callback'а.
+type OIDCRequest struct {
+	State        string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ReturnTo     string
+	UserID       *uuid.UUID
+	ExpiresAt    time.Time
+}
+
+// LoginFailures — счётчик неудачных попыток входа по одному ключу (аккаунт или IP).
+type LoginFailures struct {
+	Key          string
+	Failures     int
+	LastFailedAt time.Time
+	LockedUntil  *time.Time
+}
+
+type AuthEvent struct {
+	ID     int64
+	UserID *uuid.UUID
+	// ActorID — кто совершил действие над пользователем (администратор); nil — сам пользователь или система.
+	ActorID   *uuid.UUID
+	Type      string
+	IP        *string
+	UserAgent *string
+	Details   map[string]any
+	CreatedAt time.Time
+}
+
+// Права (scope) в токенах сервисов и интеграций: <сервис>:<действие>.
+const (
+	ScopeTrainingsRead  = "trainings:read"
+	ScopeTrainingsWrite = "trainings:write"
+	ScopeUserInfoRead   = "user-info:read"
+	ScopeUserInfoWrite  = "user-info:write"
+)
+
+// Scopes — все известные права.
+var Scopes = []string{ScopeTrainingsRead, ScopeTrainingsWrite, ScopeUserInfoRead, ScopeUserInfoWrite}
+
+// OAuthClient — сервис или интеграция с client_id и секретом (хранится только хэш).
+type OAuthClient struct {
+	ID   string
+	Name string
+	// SecretHash пуст у публичных клиентов (мобильные и браузерные приложения).
+	SecretHash []byte
+	// Scopes — права, которые клиент может запросить.
+	Scopes []string
+	// RedirectURIs — куда можно вернуть код авторизации (точное совпадение).
+	RedirectURIs []string
+	// FirstParty — наше приложение: согласие пользователя не спрашивается.
+	FirstParty bool
+	CreatedAt  time.Time
+	DisabledAt *time.Time
+}
+
+// Public сообщает, что у клиента нет секрета.
+func (c OAuthClient) Public() bool {
+	return len(c.SecretHash) == 0
+}
+
+// AuthorizationCode — одноразовый код авторизации с PKCE-челленджем (S256).
+type AuthorizationCode struct {
+	CodeHash      []byte
+	ClientID      string
+	UserID        uuid.UUID
+	RedirectURI   string
+	Scopes        []string
+	CodeChallenge string
+	// FamilyID — сессия, выпущенная по коду.
+	FamilyID   *uuid.UUID
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	ConsumedAt *time.Time
+}
+
+// MagicLink — одноразовая ссылка для входа без пароля; ID совпадает с jti токена в ссылке.
+type MagicLink struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	ConsumedAt *time.Time
+}
+
+// OAuthConsent — согласие пользователя на доступ стороннего приложения.
+type OAuthConsent struct {
+	UserID     uuid.UUID
+	ClientID   string
+	ClientName string
+	Scopes     []string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// PersonalToken — персональный токен доступа пользователя с ограниченными правами.
+type PersonalToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  []byte
+	Scopes     []string
+	ExpiresAt  time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// Mail — готовое к отправке письмо.
+type Mail struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
 }
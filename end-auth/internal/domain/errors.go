@@ -1,12 +1,76 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
 var (
-	ErrNotFound       = errors.New("not found")
-	ErrAlreadyExists  = errors.New("already exists")
-	ErrInvalidCreds   = errors.New("invalid credentials")
-	ErrBlockedUser    = errors.New("user is blocked")
-	ErrInvalidRefresh = errors.New("invalid refresh token")
-	ErrInvalidOTP     = errors.New("invalid or expired otp")
+	ErrNotFound         = errors.New("not found")
+	ErrAlreadyExists    = errors.New("already exists")
+	ErrInvalidCreds     = errors.New("invalid credentials")
+	ErrBlockedUser      = errors.New("user is blocked")
+	ErrInvalidRefresh   = errors.New("invalid refresh token")
+	ErrRefreshReuse     = errors.New("refresh token reuse detected")
+	ErrInvalidOTP       = errors.New("invalid or expired otp")
+	ErrWeakPassword     = errors.New("password does not meet requirements")
+	ErrInvalidToken     = errors.New("invalid or expired token")
+	ErrEmailNotVerified = errors.New("email is not verified")
+	ErrTooManyAttempts  = errors.New("too many failed attempts")
+	ErrInvalidMFACode   = errors.New("invalid mfa code")
+	ErrMFAEnabled       = errors.New("mfa is already enabled")
+	ErrMFANotEnabled    = errors.New("mfa is not enabled")
+	ErrUnknownProvider  = errors.New("unknown identity provider")
+	ErrIdentityConflict = errors.New("email belongs to another account")
+	ErrInvalidRedirect  = errors.New("redirect target is not allowed")
+	ErrUnknownRole      = errors.New("unknown role")
+	ErrSelfAction       = errors.New("action is not allowed on own account")
+	ErrInvalidFilter    = errors.New("invalid filter")
+	ErrInvalidClient    = errors.New("invalid oauth client")
+	ErrInvalidScope     = errors.New("invalid or not allowed scope")
+	ErrInvalidExpiry    = errors.New("invalid expiry")
+	ErrInvalidGrant     = errors.New("invalid or expired grant")
+	// ErrUnauthorizedClient — клиенту не разрешён этот способ получения токена.
+	ErrUnauthorizedClient = errors.New("client is not allowed to use this grant")
+	// ErrPasswordResetRequired — администратор потребовал сменить пароль перед входом.
+	ErrPasswordResetRequired = errors.New("password reset required")
 )
+
+// BlockedError — аккаунт заблокирован администратором; Until == nil — бессрочно.
+type BlockedError struct {
+	Until *time.Time
+}
+
+func (e *BlockedError) Error() string {
+	if e.Until == nil {
+		return ErrBlockedUser.Error()
+	}
+	return fmt.Sprintf("%s until %s", ErrBlockedUser, e.Until.Format(time.RFC3339))
+}
+
+func (e *BlockedError) Unwrap() error { return ErrBlockedUser }
+
+// PasswordPolicyError — пароль не прошёл политику; Violations — коды нарушений
+// (too_short, too_simple, breached, reused…).
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrWeakPassword, strings.Join(e.Violations, ", "))
+}
+
+func (e *PasswordPolicyError) Unwrap() error { return ErrWeakPassword }
+
+// LockedError — вход временно заблокирован после серии неудачных попыток.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.RetryAfter)
+}
+
+func (e *LockedError) Unwrap() error { return ErrTooManyAttempts }
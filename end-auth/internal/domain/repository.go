@@ -12,20 +12,185 @@ type UserRepository interface {
 	ByEmail(ctx context.Context, email string) (User, error)
 	ByID(ctx context.Context, id uuid.UUID) (User, error)
 
-	UpdatePassword(ctx context.Context, id uuid.UUID, newHash string) error
+	// UpdatePassword меняет пароль; прежний хэш сохраняется в истории, где остаются
+	// keepHistory последних (0 — история очищается).
+	UpdatePassword(ctx context.Context, id uuid.UUID, newHash string, keepHistory int) error
+	// PasswordHistory возвращает хэши до limit прежних паролей, новые первыми.
+	PasswordHistory(ctx context.Context, id uuid.UUID, limit int) ([]string, error)
+	// RehashPassword заменяет хэш того же пароля, если он всё ещё равен oldHash; флаги не трогает.
+	RehashPassword(ctx context.Context, id uuid.UUID, oldHash, newHash string) error
 	SetLastLogin(ctx context.Context, id uuid.UUID, t time.Time) error
-	SetBlocked(ctx context.Context, id uuid.UUID, blocked bool) error
+	// SetTokensValidAfter отзывает все access-токены пользователя, выпущенные до t.
+	SetTokensValidAfter(ctx context.Context, id uuid.UUID, t time.Time) error
+	// Block блокирует пользователя до until (nil — бессрочно) и пишет запись в историю.
+	Block(ctx context.Context, id uuid.UUID, until *time.Time, reason string, actor *uuid.UUID) error
+	// Unblock снимает блокировку и закрывает действующую запись истории.
+	Unblock(ctx context.Context, id uuid.UUID, actor *uuid.UUID, reason *string) error
+	ListBlocks(ctx context.Context, id uuid.UUID) ([]UserBlock, error)
+	// RequirePasswordReset закрывает вход до смены пароля; флаг снимает UpdatePassword.
+	RequirePasswordReset(ctx context.Context, id uuid.UUID) error
+	// Search возвращает страницу пользователей и общее число подходящих под фильтр.
+	Search(ctx context.Context, f UserFilter) ([]User, int, error)
+	// MarkEmailVerified подтверждает email, если он всё ещё совпадает с email и не был подтверждён.
+	MarkEmailVerified(ctx context.Context, id uuid.UUID, email string) (bool, error)
 }
 
 type RefreshRepository interface {
-	Create(ctx context.Context, userID uuid.UUID, tokenHash []byte, ua, ip *string, exp time.Time) (RefreshSession, error)
+	Create(ctx context.Context, s RefreshSession) (RefreshSession, error)
 	ByHashActive(ctx context.Context, tokenHash []byte, now time.Time) (RefreshSession, error)
+	// ByHash находит сессию независимо от того, отозвана ли она (для обнаружения повторного использования).
+	ByHash(ctx context.Context, tokenHash []byte) (RefreshSession, error)
+	// Rotate отзывает сессию при ротации; false — её уже отозвал кто-то другой.
+	Rotate(ctx context.Context, id uuid.UUID) (bool, error)
+	// FamilyActive сообщает, есть ли у семьи действующая (неотозванная и неистёкшая) сессия.
+	FamilyActive(ctx context.Context, familyID, userID uuid.UUID) (bool, error)
 	RevokeByID(ctx context.Context, id uuid.UUID) error
+	RevokeFamily(ctx context.Context, familyID uuid.UUID) error
+	// RevokeFamilyForUser отзывает семью только если она принадлежит userID; false — не найдена.
+	RevokeFamilyForUser(ctx context.Context, familyID, userID uuid.UUID) (bool, error)
 	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+	// RevokeOthersForUser отзывает все сессии пользователя, кроме семьи keepFamilyID.
+	RevokeOthersForUser(ctx context.Context, userID, keepFamilyID uuid.UUID) error
+	ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]DeviceSession, error)
+	// RevokeForUserClient отзывает сессии приложения clientID у пользователя и возвращает их семьи.
+	RevokeForUserClient(ctx context.Context, userID uuid.UUID, clientID string) ([]uuid.UUID, error)
+	RevokeForClient(ctx context.Context, clientID string) error
+}
+
+// RevokedTokenRepository — список отозванных access-токенов по jti.
+type RevokedTokenRepository interface {
+	// Revoke добавляет jti в список; userID — nil для токенов сервисов.
+	Revoke(ctx context.Context, jti string, userID *uuid.UUID, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	DeleteExpired(ctx context.Context) (int64, error)
 }
 
 type PasswordResetRepository interface {
+	// CreateOTP сохраняет новый код и аннулирует все прежние коды пользователя.
 	CreateOTP(ctx context.Context, userID uuid.UUID, otpHash string, exp time.Time) (PasswordReset, error)
 	FindValidByUser(ctx context.Context, userID uuid.UUID, now time.Time) (PasswordReset, error)
-	MarkUsed(ctx context.Context, id uuid.UUID) error
+	LatestByUser(ctx context.Context, userID uuid.UUID) (PasswordReset, error)
+	// ClaimAttempt засчитывает попытку ввода до проверки кода; ErrNotFound — код
+	// использован, истёк или maxAttempts попыток уже исчерпаны.
+	ClaimAttempt(ctx context.Context, id uuid.UUID, maxAttempts int) (PasswordReset, error)
+	// MarkUsed гасит код; false — его уже погасил параллельный запрос.
+	MarkUsed(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+type MagicLinkRepository interface {
+	Create(ctx context.Context, id, userID uuid.UUID, exp time.Time) (MagicLink, error)
+	// Consume помечает ссылку использованной; ErrNotFound — ссылки нет, она истекла или уже использована.
+	Consume(ctx context.Context, id uuid.UUID) (MagicLink, error)
+	LatestByUser(ctx context.Context, userID uuid.UUID) (MagicLink, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type MFARepository interface {
+	Get(ctx context.Context, userID uuid.UUID) (UserMFA, error)
+	// StartEnrollment сохраняет новый неподтверждённый секрет; если 2FA уже включена — ErrMFAEnabled.
+	StartEnrollment(ctx context.Context, userID uuid.UUID, secret string) error
+	Confirm(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	// UseStep атомарно принимает TOTP-интервал, только если он новее последнего принятого.
+	UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	// Delete выключает 2FA и удаляет коды восстановления.
+	Delete(ctx context.Context, userID uuid.UUID) error
+
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes [][]byte) error
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash []byte) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error)
+}
+
+type RoleRepository interface {
+	List(ctx context.Context) ([]Role, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]string, error)
+	// Grant и Revoke возвращают false, если у пользователя роль уже была (или её не было).
+	Grant(ctx context.Context, userID uuid.UUID, role string, grantedBy *uuid.UUID) (bool, error)
+	Revoke(ctx context.Context, userID uuid.UUID, role string) (bool, error)
+}
+
+type IdentityRepository interface {
+	ByProviderSubject(ctx context.Context, provider, subject string) (Identity, error)
+	Create(ctx context.Context, i Identity) (Identity, error)
+	// Touch отмечает вход через провайдера и обновляет email, который он сообщил.
+	Touch(ctx context.Context, id uuid.UUID, email *string) error
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]Identity, error)
+}
+
+type OIDCRequestRepository interface {
+	Create(ctx context.Context, r OIDCRequest) error
+	// ConsumeState возвращает запрос по state ровно один раз; просроченный — ErrNotFound.
+	ConsumeState(ctx context.Context, state, provider string) (OIDCRequest, error)
+	SetLoginCode(ctx context.Context, state string, userID uuid.UUID, codeHash []byte, exp time.Time) error
+	ConsumeLoginCode(ctx context.Context, codeHash []byte) (OIDCRequest, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// IdentityProvider — внешний OIDC-провайдер (Google, Яндекс, VK ID, ...).
+type IdentityProvider interface {
+	Name() string
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge, redirectURI string) (string, error)
+	// Exchange меняет code на токены и возвращает claims проверенного ID-токена.
+	Exchange(ctx context.Context, code, codeVerifier, redirectURI, nonce string) (ExternalIdentity, error)
+	// TrustEmail — можно ли по подтверждённому у провайдера email привязать его к существующему аккаунту.
+	TrustEmail() bool
+}
+
+// LoginThrottleRepository хранит счётчики неудачных логинов, общие для всех реплик.
+type LoginThrottleRepository interface {
+	Get(ctx context.Context, keys []string) ([]LoginFailures, error)
+	// RecordFailure увеличивает счётчик (или начинает заново, если последняя неудача была раньше resetBefore).
+	RecordFailure(ctx context.Context, key string, now, resetBefore time.Time) (int, error)
+	SetLockedUntil(ctx context.Context, key string, until time.Time) error
+	Clear(ctx context.Context, key string) error
+	// DeleteStale удаляет счётчики без неудач после before и без действующей блокировки.
+	DeleteStale(ctx context.Context, before time.Time) (int64, error)
+}
+
+type OAuthClientRepository interface {
+	Create(ctx context.Context, c OAuthClient) (OAuthClient, error)
+	ByID(ctx context.Context, id string) (OAuthClient, error)
+	List(ctx context.Context) ([]OAuthClient, error)
+	// Disable отключает клиента; false — клиент не найден или уже отключён.
+	Disable(ctx context.Context, id string) (bool, error)
+}
+
+type AuthorizationCodeRepository interface {
+	Create(ctx context.Context, c AuthorizationCode) error
+	// Consume помечает код использованным; ErrNotFound — кода нет, он истёк или уже использован.
+	Consume(ctx context.Context, codeHash []byte) (AuthorizationCode, error)
+	ByHash(ctx context.Context, codeHash []byte) (AuthorizationCode, error)
+	SetFamily(ctx context.Context, codeHash []byte, familyID uuid.UUID) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type ConsentRepository interface {
+	// Grant добавляет права к согласию пользователя на доступ приложения.
+	Grant(ctx context.Context, userID uuid.UUID, clientID string, scopes []string) error
+	Get(ctx context.Context, userID uuid.UUID, clientID string) (OAuthConsent, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]OAuthConsent, error)
+	// Delete удаляет согласие; false — его не было.
+	Delete(ctx context.Context, userID uuid.UUID, clientID string) (bool, error)
+}
+
+type PersonalTokenRepository interface {
+	Create(ctx context.Context, t PersonalToken) (PersonalToken, error)
+	// ByHashActive находит неотозванный и неистёкший токен.
+	ByHashActive(ctx context.Context, tokenHash []byte) (PersonalToken, error)
+	// ListByUser возвращает неотозванные токены пользователя, включая истёкшие.
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]PersonalToken, error)
+	// Revoke отзывает токен пользователя; false — не найден.
+	Revoke(ctx context.Context, id, userID uuid.UUID) (bool, error)
+	Touch(ctx context.Context, id uuid.UUID) error
+}
+
+type EventRepository interface {
+	Record(ctx context.Context, e AuthEvent) error
+	// ListByUser возвращает последние события пользователя, новые сверху.
+	ListByUser(ctx context.Context, userID uuid.UUID, limit int) ([]AuthEvent, error)
+	Search(ctx context.Context, f EventFilter) ([]AuthEvent, error)
+}
+
+// Mailer — порт доставки писем (SMTP, локальный outbox и т.п.).
+type Mailer interface {
+	Send(ctx context.Context, m Mail) error
 }
@@ -0,0 +1,144 @@
+package keys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ManifestFile лежит в каталоге ключей рядом с <kid>.pem и описывает расписание ротации.
+const ManifestFile = "keyring.json"
+
+type Manifest struct {
+	Keys []ManifestEntry `json:"keys"`
+}
+
+type ManifestEntry struct {
+	KID        string    `json:"kid"`
+	Alg        string    `json:"alg"`
+	ActivateAt time.Time `json:"activate_at"`
+}
+
+func ReadManifest(dir string) (Manifest, error) {
+	var m Manifest
+	raw, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return m, fmt.Errorf("%s: %w", ManifestFile, err)
+	}
+	return m, nil
+}
+
+func WriteManifest(dir string, m Manifest) error {
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, ManifestFile+".tmp")
+	if err := os.WriteFile(tmp, append(raw, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, ManifestFile))
+}
+
+// LoadDir читает манифест и приватные ключи из каталога.
+func LoadDir(dir string) ([]Entry, error) {
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(m.Keys))
+	for _, me := range m.Keys {
+		k, err := LoadFile(me.KID, me.Alg, pemPath(dir, me.KID))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, Entry{Key: k, ActivateAt: me.ActivateAt})
+	}
+	return entries, nil
+}
+
+// AddToDir сохраняет новый ключ и ставит его активацию в расписание.
+func AddToDir(dir string, k *Key, activateAt time.Time) error {
+	if k.ID == "" || strings.ContainsAny(k.ID, `/\.`) {
+		return fmt.Errorf("invalid kid %q", k.ID)
+	}
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return err
+	}
+	for _, me := range m.Keys {
+		if me.KID == k.ID {
+			return fmt.Errorf("kid %q already exists", k.ID)
+		}
+	}
+
+	raw, err := k.MarshalPEM()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(pemPath(dir, k.ID), raw, 0o600); err != nil {
+		return err
+	}
+
+	m.Keys = append(m.Keys, ManifestEntry{KID: k.ID, Alg: k.Method.Alg(), ActivateAt: activateAt.UTC()})
+	return WriteManifest(dir, m)
+}
+
+// PruneDir удаляет из каталога ключи, которые уже вышли из ротации.
+func PruneDir(dir string, retention time.Duration, now time.Time) ([]string, error) {
+	entries, err := LoadDir(dir)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	ring, err := NewRing(retention, entries)
+	if err != nil {
+		return nil, err
+	}
+	states := ring.States(now)
+
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		kept    []ManifestEntry
+		removed []string
+	)
+	for _, me := range m.Keys {
+		if states[me.KID] == StateRetired {
+			removed = append(removed, me.KID)
+			continue
+		}
+		kept = append(kept, me)
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	m.Keys = kept
+	if err := WriteManifest(dir, m); err != nil {
+		return nil, err
+	}
+	for _, kid := range removed {
+		_ = os.Remove(pemPath(dir, kid))
+	}
+	return removed, nil
+}
+
+func pemPath(dir, kid string) string {
+	return filepath.Join(dir, kid+".pem")
+}
@@ -0,0 +1,150 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+
+	rsaBits = 2048
+)
+
+var ErrUnsupportedAlg = errors.New("unsupported signing algorithm")
+
+// Key — асимметричный ключ подписи access-токенов.
+// Публичная часть публикуется в JWKS, приватная никогда не покидает сервис.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+}
+
+func (k *Key) Public() crypto.PublicKey {
+	return k.Private.Public()
+}
+
+// Generate создаёт новый ключ для указанного алгоритма.
+func Generate(id, alg string) (*Key, error) {
+	method, err := methodFor(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	var priv crypto.Signer
+	switch alg {
+	case AlgRS256:
+		priv, err = rsa.GenerateKey(rand.Reader, rsaBits)
+	case AlgEdDSA:
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Key{ID: id, Method: method, Private: priv}, nil
+}
+
+// LoadFile читает приватный ключ в PEM (PKCS#8) и проверяет, что он подходит под alg.
+func LoadFile(id, alg, path string) (*Key, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(id, alg, raw)
+}
+
+func Parse(id, alg string, pemBytes []byte) (*Key, error) {
+	method, err := methodFor(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("key %q: no PEM block found", id)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", id, err)
+	}
+
+	var priv crypto.Signer
+	switch p := parsed.(type) {
+	case *rsa.PrivateKey:
+		if alg == AlgRS256 {
+			priv = p
+		}
+	case ed25519.PrivateKey:
+		if alg == AlgEdDSA {
+			priv = p
+		}
+	}
+	if priv == nil {
+		return nil, fmt.Errorf("key %q: %T does not match alg %s", id, parsed, alg)
+	}
+	return &Key{ID: id, Method: method, Private: priv}, nil
+}
+
+// MarshalPEM кодирует приватный ключ в PEM (PKCS#8).
+func (k *Key) MarshalPEM() ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.Private)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// JWK — публичный ключ в формате RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+func (k *Key) JWK() JWK {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Method.Alg()}
+	switch pub := k.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64(pub.N.Bytes())
+		jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64(pub)
+	}
+	return jwk
+}
+
+func methodFor(alg string) (jwt.SigningMethod, error) {
+	switch alg {
+	case AlgRS256:
+		return jwt.SigningMethodRS256, nil
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlg, alg)
+	}
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,133 @@
+package keys
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Состояния ключа в жизненном цикле ротации.
+const (
+	StatePending  = "pending"  // опубликован в JWKS, но ещё не подписывает
+	StateActive   = "active"   // текущий ключ подписи
+	StateRetiring = "retiring" // заменён, но ещё проверяет выпущенные им токены
+	StateRetired  = "retired"  // больше не используется
+)
+
+var ErrNoActiveKey = errors.New("no active signing key")
+
+// Entry — ключ и момент, с которого он становится ключом подписи.
+type Entry struct {
+	Key        *Key
+	ActivateAt time.Time
+}
+
+// Ring хранит несколько ключей одновременно. Подписывает всегда самый свежий
+// активированный ключ; предыдущий остаётся в JWKS ещё retention после замены,
+// чтобы выпущенные им токены успели истечь.
+type Ring struct {
+	mu        sync.RWMutex
+	entries   []Entry
+	retention time.Duration
+}
+
+func NewRing(retention time.Duration, entries []Entry) (*Ring, error) {
+	r := &Ring{retention: retention}
+	if err := r.Replace(entries); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Replace атомарно подменяет набор ключей (используется при перечитывании каталога).
+func (r *Ring) Replace(entries []Entry) error {
+	if len(entries) == 0 {
+		return errors.New("keyring is empty")
+	}
+	sorted := make([]Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ActivateAt.Before(sorted[j].ActivateAt)
+	})
+
+	seen := make(map[string]struct{}, len(sorted))
+	for _, e := range sorted {
+		if _, dup := seen[e.Key.ID]; dup {
+			return fmt.Errorf("duplicate kid %q", e.Key.ID)
+		}
+		seen[e.Key.ID] = struct{}{}
+	}
+
+	r.mu.Lock()
+	r.entries = sorted
+	r.mu.Unlock()
+	return nil
+}
+
+// Signing возвращает ключ, которым нужно подписывать токены в момент now.
+func (r *Ring) Signing(now time.Time) (*Key, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if !r.entries[i].ActivateAt.After(now) {
+			return r.entries[i].Key, nil
+		}
+	}
+	return nil, ErrNoActiveKey
+}
+
+// Verification возвращает ключи, которые публикуются в JWKS и принимаются при проверке.
+func (r *Ring) Verification(now time.Time) []*Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]*Key, 0, len(r.entries))
+	for i := range r.entries {
+		if r.state(i, now) != StateRetired {
+			out = append(out, r.entries[i].Key)
+		}
+	}
+	return out
+}
+
+// Lookup ищет ключ проверки по kid.
+func (r *Ring) Lookup(kid string, now time.Time) (*Key, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for i := range r.entries {
+		if r.entries[i].Key.ID == kid && r.state(i, now) != StateRetired {
+			return r.entries[i].Key, true
+		}
+	}
+	return nil, false
+}
+
+// States возвращает состояние каждого ключа на момент now (для административных утилит).
+func (r *Ring) States(now time.Time) map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make(map[string]string, len(r.entries))
+	for i := range r.entries {
+		out[r.entries[i].Key.ID] = r.state(i, now)
+	}
+	return out
+}
+
+func (r *Ring) state(i int, now time.Time) string {
+	e := r.entries[i]
+	if e.ActivateAt.After(now) {
+		return StatePending
+	}
+	if i+1 == len(r.entries) || r.entries[i+1].ActivateAt.After(now) {
+		return StateActive
+	}
+	if now.Before(r.entries[i+1].ActivateAt.Add(r.retention)) {
+		return StateRetiring
+	}
+	return StateRetired
+}
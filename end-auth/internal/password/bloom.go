@@ -0,0 +1,117 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Bloom — фильтр Блума по SHA-1 паролей: тот же хэш, что в выгрузках Have I Been Pwned,
+// поэтому корпус можно собрать и из паролей, и из готовых SHA-1. Ложноположительные
+// срабатывания возможны (пароль отклонят зря), ложноотрицательные — нет.
+//
+// Формат файла: "ENBF1", m (uint64, бит), k (uint32, хэш-функций), n (uint64, записей), биты.
+type Bloom struct {
+	m    uint64
+	k    uint32
+	n    uint64
+	bits []uint64
+}
+
+const bloomMagic = "ENBF1"
+
+var ErrBadBloom = errors.New("password: invalid bloom filter file")
+
+// NewBloom создаёт пустой фильтр на n записей с долей ложных срабатываний fp.
+func NewBloom(n uint64, fp float64) *Bloom {
+	n = max(n, 1)
+	if fp <= 0 || fp >= 1 {
+		fp = 0.001
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(fp) / (math.Ln2 * math.Ln2)))
+	k := uint32(max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return &Bloom{m: m, k: k, bits: make([]uint64, (m+63)/64)}
+}
+
+// Add добавляет SHA-1 пароля.
+func (b *Bloom) Add(sum [sha1.Size]byte) {
+	h1, h2 := bloomHashes(sum)
+	for i := uint64(0); i < uint64(b.k); i++ {
+		j := (h1 + i*h2) % b.m
+		b.bits[j/64] |= 1 << (j % 64)
+	}
+	b.n++
+}
+
+// Contains сообщает, есть ли пароль в корпусе (с точностью до ложных срабатываний).
+func (b *Bloom) Contains(pw string) bool {
+	h1, h2 := bloomHashes(sha1.Sum([]byte(pw)))
+	for i := uint64(0); i < uint64(b.k); i++ {
+		j := (h1 + i*h2) % b.m
+		if b.bits[j/64]&(1<<(j%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Len — сколько записей добавлено в фильтр.
+func (b *Bloom) Len() uint64 { return b.n }
+
+// двойное хэширование (Kirsch–Mitzenmacher): SHA-1 уже равномерен, его половин хватает
+func bloomHashes(sum [sha1.Size]byte) (uint64, uint64) {
+	return binary.LittleEndian.Uint64(sum[0:8]), binary.LittleEndian.Uint64(sum[8:16]) | 1
+}
+
+func (b *Bloom) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	hdr := make([]byte, 0, len(bloomMagic)+20)
+	hdr = append(hdr, bloomMagic...)
+	hdr = binary.LittleEndian.AppendUint64(hdr, b.m)
+	hdr = binary.LittleEndian.AppendUint32(hdr, b.k)
+	hdr = binary.LittleEndian.AppendUint64(hdr, b.n)
+	if _, err := bw.Write(hdr); err != nil {
+		return 0, err
+	}
+	if err := binary.Write(bw, binary.LittleEndian, b.bits); err != nil {
+		return 0, err
+	}
+	return int64(len(hdr) + 8*len(b.bits)), bw.Flush()
+}
+
+// LoadBloom читает фильтр из файла целиком в память.
+func LoadBloom(path string) (*Bloom, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	hdr := make([]byte, len(bloomMagic)+20)
+	if _, err := io.ReadFull(r, hdr); err != nil || string(hdr[:len(bloomMagic)]) != bloomMagic {
+		return nil, ErrBadBloom
+	}
+	hdr = hdr[len(bloomMagic):]
+	b := &Bloom{
+		m: binary.LittleEndian.Uint64(hdr[0:8]),
+		k: binary.LittleEndian.Uint32(hdr[8:12]),
+		n: binary.LittleEndian.Uint64(hdr[12:20]),
+	}
+	if b.m == 0 || b.k == 0 || b.k > 64 {
+		return nil, ErrBadBloom
+	}
+	if st, err := f.Stat(); err == nil && uint64(st.Size()) != uint64(len(bloomMagic)+20)+(b.m+63)/64*8 {
+		return nil, fmt.Errorf("%w: size mismatch", ErrBadBloom)
+	}
+	b.bits = make([]uint64, (b.m+63)/64)
+	if err := binary.Read(r, binary.LittleEndian, b.bits); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadBloom, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,140 @@
+package password
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Коды нарушений политики; уходят клиенту как есть.
+const (
+	TooShort      = "too_short"
+	TooLong       = "too_long"
+	TooSimple     = "too_simple"
+	ContainsEmail = "contains_email"
+	Breached      = "breached"
+	// Reused проверяется сервисом по истории паролей пользователя.
+	Reused = "reused"
+)
+
+// PolicyConfig — требования к новым паролям.
+type PolicyConfig struct {
+	// MinLength и MaxLength — в символах; по умолчанию 8 и 256.
+	MinLength int
+	MaxLength int
+	// MinEntropy — минимальная оценка стойкости в битах (см. Entropy); 0 — не проверять.
+	MinEntropy float64
+	// ForbidEmail запрещает пароли, содержащие имя из email (часть до @).
+	ForbidEmail bool
+	// History — сколько последних паролей, включая текущий, нельзя использовать снова; 0 — история не хранится.
+	History int
+	// BreachedFile — фильтр Блума утёкших паролей (authctl passwords build-breached); пусто — не проверять.
+	BreachedFile string
+}
+
+// Policy проверяет пароль без обращения к хранилищу; повторы проверяет сервис.
+type Policy struct {
+	cfg      PolicyConfig
+	breached *Bloom
+}
+
+// NewPolicy загружает корпус утёкших паролей, если он задан.
+func NewPolicy(cfg PolicyConfig) (*Policy, error) {
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = 8
+	}
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = 256
+	}
+	p := &Policy{cfg: cfg}
+	if cfg.BreachedFile != "" {
+		b, err := LoadBloom(cfg.BreachedFile)
+		if err != nil {
+			return nil, err
+		}
+		p.breached = b
+	}
+	return p, nil
+}
+
+// History — сколько последних паролей сравнивать с новым.
+func (p *Policy) History() int { return p.cfg.History }
+
+// Check возвращает нарушения политики; пустой результат — пароль подходит.
+func (p *Policy) Check(pw, email string) []string {
+	var out []string
+	n := utf8.RuneCountInString(pw)
+	if n < p.cfg.MinLength {
+		out = append(out, TooShort)
+	}
+	if n > p.cfg.MaxLength {
+		// дальше не считаем: длинный ввод не должен стоить лишней работы
+		return append(out, TooLong)
+	}
+	if p.cfg.MinEntropy > 0 && Entropy(pw) < p.cfg.MinEntropy {
+		out = append(out, TooSimple)
+	}
+	if p.cfg.ForbidEmail && containsEmailName(pw, email) {
+		out = append(out, ContainsEmail)
+	}
+	if p.breached != nil && p.breached.Contains(pw) {
+		out = append(out, Breached)
+	}
+	return out
+}
+
+// Entropy — грубая оценка стойкости в битах: каждый символ даёт log2 размера
+// алфавита из встречающихся классов (строчные, прописные, цифры, прочее),
+// а повтор предыдущего символа или шаг последовательности (abc, 321) — 1 бит.
+func Entropy(pw string) float64 {
+	var lower, upper, digit, other bool
+	for _, r := range pw {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		default:
+			other = true
+		}
+	}
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if other {
+		pool += 33
+	}
+	if pool == 0 {
+		return 0
+	}
+	perChar := math.Log2(float64(pool))
+
+	var bits float64
+	prev := rune(-1)
+	for _, r := range pw {
+		r = unicode.ToLower(r)
+		if d := r - prev; prev >= 0 && d >= -1 && d <= 1 {
+			bits++
+		} else {
+			bits += perChar
+		}
+		prev = r
+	}
+	return bits
+}
+
+// containsEmailName: короткие имена (до трёх символов) не проверяем — слишком много совпадений.
+func containsEmailName(pw, email string) bool {
+	name, _, _ := strings.Cut(strings.ToLower(email), "@")
+	return utf8.RuneCountInString(name) >= 3 && strings.Contains(strings.ToLower(pw), name)
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"auth/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultUsersPage = 50
+	maxUsersPage     = 200
+)
+
+// UserDetails — карточка пользователя для админки.
+type UserDetails struct {
+	User       domain.User
+	Roles      []string
+	MFAEnabled bool
+}
+
+// UserPage — страница поиска; Limit и Offset — фактически применённые.
+type UserPage struct {
+	Users  []domain.User
+	Total  int
+	Limit  int
+	Offset int
+}
+
+// ListUsers ищет пользователей по подстроке email и статусу блокировки.
+func (s *Service) ListUsers(ctx context.Context, f domain.UserFilter) (UserPage, error) {
+	f.Query = strings.TrimSpace(f.Query)
+	if f.Limit <= 0 {
+		f.Limit = defaultUsersPage
+	}
+	if f.Limit > maxUsersPage {
+		f.Limit = maxUsersPage
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	users, total, err := s.users.Search(ctx, f)
+	if err != nil {
+		return UserPage{}, err
+	}
+	return UserPage{Users: users, Total: total, Limit: f.Limit, Offset: f.Offset}, nil
+}
+
+func (s *Service) GetUser(ctx context.Context, userID uuid.UUID) (UserDetails, error) {
+	u, err := s.users.ByID(ctx, userID)
+	if err != nil {
+		return UserDetails{}, err
+	}
+	roles, err := s.roles.ListByUser(ctx, userID)
+	if err != nil {
+		return UserDetails{}, err
+	}
+	st, err := s.MFAStatus(ctx, userID)
+	if err != nil {
+		return UserDetails{}, err
+	}
+	return UserDetails{User: u, Roles: roles, MFAEnabled: st.Enabled}, nil
+}
+
+// BlockUser блокирует аккаунт до until (nil — бессрочно) и завершает все его сессии.
+func (s *Service) BlockUser(ctx context.Context, actor, userID uuid.UUID, reason string, until *time.Time) error {
+	if actor == userID {
+		return domain.ErrSelfAction
+	}
+	if _, err := s.users.ByID(ctx, userID); err != nil {
+		return err
+	}
+	reason = strings.TrimSpace(reason)
+	if err := s.users.Block(ctx, userID, until, reason, &actor); err != nil {
+		return err
+	}
+	if err := s.refresh.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.revokeAllAccess(ctx, userID); err != nil {
+		return err
+	}
+	details := map[string]any{"reason": reason}
+	if until != nil {
+		details["until"] = until.UTC().Format(time.RFC3339)
+	}
+	log.Info().Str("user_id", userID.String()).Str("actor_id", actor.String()).Msg("user blocked by admin")
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  &userID,
+		ActorID: &actor,
+		Type:    domain.EventAdminBlocked,
+		Details: details,
+	})
+	return nil
+}
+
+func (s *Service) UnblockUser(ctx context.Context, actor, userID uuid.UUID, reason string) error {
+	if _, err := s.users.ByID(ctx, userID); err != nil {
+		return err
+	}
+	reason = strings.TrimSpace(reason)
+	if err := s.users.Unblock(ctx, userID, &actor, optString(reason)); err != nil {
+		return err
+	}
+	log.Info().Str("user_id", userID.String()).Str("actor_id", actor.String()).Msg("user unblocked by admin")
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  &userID,
+		ActorID: &actor,
+		Type:    domain.EventAdminUnblocked,
+		Details: map[string]any{"reason": reason},
+	})
+	return nil
+}
+
+// UserBlocks возвращает историю блокировок, новые сверху.
+func (s *Service) UserBlocks(ctx context.Context, userID uuid.UUID) ([]domain.UserBlock, error) {
+	if _, err := s.users.ByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.users.ListBlocks(ctx, userID)
+}
+
+// ForcePasswordReset закрывает вход по старому паролю, завершает сессии и отправляет
+// пользователю код сброса (без учёта ResetOTPCooldown). Код возвращается только в DevMode.
+func (s *Service) ForcePasswordReset(ctx context.Context, actor, userID uuid.UUID) (string, error) {
+	u, err := s.users.ByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if err := s.users.RequirePasswordReset(ctx, userID); err != nil {
+		return "", err
+	}
+	if err := s.refresh.RevokeAllForUser(ctx, userID); err != nil {
+		return "", err
+	}
+	if err := s.revokeAllAccess(ctx, userID); err != nil {
+		return "", err
+	}
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  &userID,
+		ActorID: &actor,
+		Type:    domain.EventAdminPasswordReset,
+	})
+	return s.sendResetCode(ctx, u)
+}
+
+// RevokeUserSessions разлогинивает пользователя на всех устройствах.
+func (s *Service) RevokeUserSessions(ctx context.Context, actor, userID uuid.UUID) error {
+	if _, err := s.users.ByID(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.refresh.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.revokeAllAccess(ctx, userID); err != nil {
+		return err
+	}
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  &userID,
+		ActorID: &actor,
+		Type:    domain.EventAdminSessionsRevoke,
+	})
+	return nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+
+	"auth/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultActivityLimit = 20
+	maxActivityLimit     = 100
+	defaultEventsPage    = 50
+	maxEventsPage        = 500
+)
+
+type clientKey struct{}
+
+// WithClient кладёт в контекст адрес и user agent запроса: recordEvent пишет их в журнал
+// для любых событий, в том числе там, где ClientInfo не передаётся явно.
+func WithClient(ctx context.Context, client ClientInfo) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+func clientFrom(ctx context.Context) ClientInfo {
+	c, _ := ctx.Value(clientKey{}).(ClientInfo)
+	return c
+}
+
+// SecurityActivity возвращает последние события безопасности пользователя.
+func (s *Service) SecurityActivity(ctx context.Context, userID uuid.UUID, limit int) ([]domain.AuthEvent, error) {
+	if limit <= 0 {
+		limit = defaultActivityLimit
+	}
+	if limit > maxActivityLimit {
+		limit = maxActivityLimit
+	}
+	return s.events.ListByUser(ctx, userID, limit)
+}
+
+type EventPage struct {
+	Events []domain.AuthEvent
+	// NextBeforeID — курсор следующей страницы, 0 если страница последняя.
+	NextBeforeID int64
+}
+
+// SearchEvents — поиск по журналу для админки, новые сверху.
+func (s *Service) SearchEvents(ctx context.Context, f domain.EventFilter) (EventPage, error) {
+	if f.Limit <= 0 {
+		f.Limit = defaultEventsPage
+	}
+	if f.Limit > maxEventsPage {
+		f.Limit = maxEventsPage
+	}
+	if f.Since != nil && f.Until != nil && !f.Since.Before(*f.Until) {
+		return EventPage{}, domain.ErrInvalidFilter
+	}
+	events, err := s.events.Search(ctx, f)
+	if err != nil {
+		return EventPage{}, err
+	}
+	page := EventPage{Events: events}
+	if len(events) == f.Limit {
+		page.NextBeforeID = events[len(events)-1].ID
+	}
+	return page, nil
+}
+
+// loginFailed пишет неудачную попытку входа; userID — nil, если аккаунт не найден.
+func (s *Service) loginFailed(ctx context.Context, userID *uuid.UUID, email, reason string) {
+	details := map[string]any{"reason": reason}
+	if email != "" {
+		details["email"] = email
+	}
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  userID,
+		Type:    domain.EventLoginFailure,
+		Details: details,
+	})
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"auth/internal/domain"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const tmplMagicLink = "magic_link"
+
+// MagicLinkConfig — вход по ссылке из письма, без пароля.
+type MagicLinkConfig struct {
+	// TTL — сколько действует ссылка; по умолчанию 15 минут.
+	TTL time.Duration
+	// LinkURL — страница фронтенда из письма; токен передаётся в параметре token.
+	// Пусто — в письме только токен.
+	LinkURL string
+	// Cooldown — минимальный интервал между письмами одному пользователю.
+	Cooldown time.Duration
+}
+
+// StartMagicLink отправляет письмо со ссылкой для входа. Для неизвестных и
+// заблокированных адресов молча ничего не делает, чтобы не раскрывать, есть ли аккаунт.
+// Токен возвращается только в svc.devMode.
+func (s *Service) StartMagicLink(ctx context.Context, email string) (string, error) {
+	email = normEmail(email)
+	u, err := s.users.ByEmail(ctx, email)
+	if err != nil {
+		return "", nil
+	}
+	if checkBlocked(u) != nil {
+		log.Info().Str("user_id", u.ID.String()).Msg("magic link requested for blocked user, skipping")
+		return "", nil
+	}
+	if s.cfg.MagicLink.Cooldown > 0 {
+		last, err := s.magicLinks.LatestByUser(ctx, u.ID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return "", err
+		}
+		if err == nil && time.Since(last.CreatedAt) < s.cfg.MagicLink.Cooldown {
+			log.Info().Str("user_id", u.ID.String()).Msg("magic link requested during cooldown, skipping")
+			return "", nil
+		}
+	}
+
+	ttl := s.cfg.magicLinkTTL()
+	claims := jwt.MapClaims{
+		"sub":   u.ID.String(),
+		"email": email,
+	}
+	token, err := s.signToken(typMagicLink, ttl, claims)
+	if err != nil {
+		return "", err
+	}
+	// signToken дописывает jti в claims — по нему ссылка помечается использованной
+	jti, err := uuid.Parse(claims["jti"].(string))
+	if err != nil {
+		return "", err
+	}
+	_, _ = s.magicLinks.DeleteExpired(ctx)
+	if _, err := s.magicLinks.Create(ctx, jti, u.ID, time.Now().Add(ttl)); err != nil {
+		return "", err
+	}
+
+	var link string
+	if s.cfg.MagicLink.LinkURL != "" {
+		link, err = withQuery(s.cfg.MagicLink.LinkURL, "token", token)
+		if err != nil {
+			return "", err
+		}
+	}
+	err = s.sendMail(ctx, tmplMagicLink, u.Email, map[string]any{
+		"Email": u.Email,
+		"Link":  link,
+		"Token": token,
+		"TTL":   humanTTL(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	s.recordEvent(ctx, domain.AuthEvent{UserID: &u.ID, Type: domain.EventMagicLinkSent})
+	if s.cfg.DevMode {
+		return token, nil
+	}
+	return "", nil
+}
+
+// ConfirmMagicLink входит по токену из письма. Ссылка одноразовая; неудачные попытки
+// считаются и блокируют вход так же, как неверный пароль в Login. Переход по ссылке
+// подтверждает email.
+func (s *Service) ConfirmMagicLink(ctx context.Context, token string, client ClientInfo) (LoginResult, error) {
+	claims, parseErr := s.parseToken(token, typMagicLink)
+	// email берём только из токена с верной подписью; иначе аккаунт неизвестен и считаем по IP
+	email, _ := claims["email"].(string)
+	throttle := s.loginKeys(email, client.IP)
+	if email == "" {
+		throttle = throttle[1:]
+	}
+	if err := s.checkThrottle(ctx, throttle); err != nil {
+		s.loginFailed(ctx, nil, email, "locked")
+		return LoginResult{}, err
+	}
+
+	subStr, _ := claims["sub"].(string)
+	jtiStr, _ := claims["jti"].(string)
+	id, err := uuid.Parse(subStr)
+	jti, jtiErr := uuid.Parse(jtiStr)
+	if parseErr != nil || err != nil || jtiErr != nil || email == "" {
+		s.recordFailure(ctx, throttle, nil)
+		s.loginFailed(ctx, nil, "", "invalid_magic_link")
+		return LoginResult{}, domain.ErrInvalidToken
+	}
+
+	link, err := s.magicLinks.Consume(ctx, jti)
+	if errors.Is(err, domain.ErrNotFound) {
+		// подпись верна, значит ссылкой уже воспользовались
+		log.Warn().Str("user_id", id.String()).Str("link_id", jti.String()).Msg("magic link reused")
+		s.recordFailure(ctx, throttle, &id)
+		s.loginFailed(ctx, &id, "", "magic_link_reused")
+		return LoginResult{}, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	u, err := s.users.ByID(ctx, link.UserID)
+	if err != nil || u.ID != id || normEmail(u.Email) != email {
+		// email сменили после отправки письма
+		s.recordFailure(ctx, throttle, &id)
+		s.loginFailed(ctx, &id, "", "invalid_magic_link")
+		return LoginResult{}, domain.ErrInvalidToken
+	}
+	if err := checkBlocked(u); err != nil {
+		s.loginFailed(ctx, &u.ID, "", "blocked")
+		return LoginResult{}, err
+	}
+	if u.EmailVerifiedAt == nil {
+		ok, err := s.users.MarkEmailVerified(ctx, u.ID, email)
+		if err != nil {
+			return LoginResult{}, err
+		}
+		if ok {
+			now := time.Now()
+			u.EmailVerifiedAt = &now
+			s.recordEvent(ctx, domain.AuthEvent{
+				UserID:  &u.ID,
+				Type:    domain.EventEmailVerified,
+				Details: map[string]any{"email": email},
+			})
+		}
+	}
+	s.clearFailures(ctx, throttle[0].key)
+	return s.finishLogin(ctx, u, "magic_link", client)
+}
+
+func (c Config) magicLinkTTL() time.Duration {
+	if c.MagicLink.TTL <= 0 {
+		return 15 * time.Minute
+	}
+	return c.MagicLink.TTL
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"auth/internal/domain"
+	"auth/internal/totp"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// MFAConfig — второй фактор (TOTP) и коды восстановления.
+type MFAConfig struct {
+	// Issuer — название сервиса в приложении-аутентификаторе.
+	Issuer string
+	// ChallengeTTL — сколько живёт токен между вводом пароля и кода 2FA.
+	ChallengeTTL time.Duration
+	// Skew — сколько соседних 30-секундных интервалов принимать из-за расхождения часов.
+	Skew          int
+	RecoveryCodes int
+}
+
+type MFAStatus struct {
+	Enabled           bool
+	RecoveryCodesLeft int
+}
+
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func (s *Service) MFAStatus(ctx context.Context, userID uuid.UUID) (MFAStatus, error) {
+	m, err := s.mfa.Get(ctx, userID)
+	if errors.Is(err, domain.ErrNotFound) || (err == nil && !m.Enabled()) {
+		return MFAStatus{}, nil
+	}
+	if err != nil {
+		return MFAStatus{}, err
+	}
+	left, err := s.mfa.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return MFAStatus{}, err
+	}
+	return MFAStatus{Enabled: true, RecoveryCodesLeft: left}, nil
+}
+
+// EnrollTOTP выдаёт новый секрет. 2FA включится только после ConfirmTOTP;
+// повторный вызов до подтверждения заменяет секрет.
+func (s *Service) EnrollTOTP(ctx context.Context, userID uuid.UUID) (TOTPEnrollment, error) {
+	u, err := s.users.ByID(ctx, userID)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	if err := s.mfa.StartEnrollment(ctx, userID, secret); err != nil {
+		return TOTPEnrollment{}, err
+	}
+	return TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.cfg.MFA.Issuer, u.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP включает 2FA по первому коду из приложения и возвращает коды восстановления.
+// Коды показываются один раз, в БД хранятся только их хэши.
+func (s *Service) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	m, err := s.mfa.Get(ctx, userID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.ErrMFANotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.Enabled() {
+		return nil, domain.ErrMFAEnabled
+	}
+
+	step, ok := totp.Validate(m.TOTPSecret, code, time.Now(), s.cfg.MFA.Skew)
+	if !ok {
+		return nil, domain.ErrInvalidMFACode
+	}
+	confirmed, err := s.mfa.Confirm(ctx, userID, step)
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		return nil, domain.ErrMFAEnabled
+	}
+
+	codes, err := s.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.recordEvent(ctx, domain.AuthEvent{UserID: &userID, Type: domain.EventMFAEnabled})
+	return codes, nil
+}
+
+// DisableTOTP выключает 2FA. Требует повторной аутентификации: пароль и код
+// из приложения (или код восстановления).
+func (s *Service) DisableTOTP(ctx context.Context, userID uuid.UUID, password, code string) error {
+	m, err := s.reauthenticate(ctx, userID, password, code)
+	if err != nil {
+		return err
+	}
+	if err := s.mfa.Delete(ctx, m.UserID); err != nil {
+		return err
+	}
+	s.recordEvent(ctx, domain.AuthEvent{UserID: &userID, Type: domain.EventMFADisabled})
+	return nil
+}
+
+// RegenerateRecoveryCodes выдаёт новый набор кодов восстановления; старые перестают действовать.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, password, code string) ([]string, error) {
+	if _, err := s.reauthenticate(ctx, userID, password, code); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// CompleteMFALogin завершает вход по токену MFA-челленджа из Login и второму фактору.
+// Неверные коды учитываются тем же механизмом, что и неверные пароли.
+func (s *Service) CompleteMFALogin(ctx context.Context, challenge, code string, client ClientInfo) (TokenPair, error) {
+	claims, err := s.parseToken(challenge, typMFAChallenge)
+	if err != nil {
+		return TokenPair{}, domain.ErrInvalidToken
+	}
+	subStr, _ := claims["sub"].(string)
+	userID, err := uuid.Parse(subStr)
+	if err != nil {
+		return TokenPair{}, domain.ErrInvalidToken
+	}
+
+	throttle := []throttleKey{{key: "mfa:" + userID.String(), rule: s.cfg.LoginThrottle.Account}}
+	if client.IP != "" {
+		throttle = append(throttle, throttleKey{key: "ip:" + client.IP, rule: s.cfg.LoginThrottle.IP})
+	}
+	if err := s.checkThrottle(ctx, throttle); err != nil {
+		return TokenPair{}, err
+	}
+
+	u, err := s.users.ByID(ctx, userID)
+	if err != nil {
+		return TokenPair{}, domain.ErrInvalidToken
+	}
+	if err := checkBlocked(u); err != nil {
+		return TokenPair{}, err
+	}
+	m, err := s.mfa.Get(ctx, userID)
+	if err != nil || !m.Enabled() {
+		// 2FA выключили, пока пользователь вводил код
+		return TokenPair{}, domain.ErrInvalidToken
+	}
+
+	if err := s.verifySecondFactor(ctx, m, code); err != nil {
+		if errors.Is(err, domain.ErrInvalidMFACode) {
+			s.recordFailure(ctx, throttle, &userID)
+			s.loginFailed(ctx, &userID, "", "invalid_mfa_code")
+		}
+		return TokenPair{}, err
+	}
+	s.clearFailures(ctx, throttle[0].key)
+
+	_ = s.users.SetLastLogin(ctx, u.ID, time.Now().UTC())
+	tp, err := s.issuePair(ctx, u, nil, client)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  &u.ID,
+		Type:    domain.EventLoginSuccess,
+		Details: map[string]any{"method": "mfa"},
+	})
+	return tp, nil
+}
+
+// mfaChallenge возвращает токен челленджа, если у пользователя включена 2FA, иначе пустую строку.
+func (s *Service) mfaChallenge(ctx context.Context, u domain.User) (string, error) {
+	m, err := s.mfa.Get(ctx, u.ID)
+	if errors.Is(err, domain.ErrNotFound) || (err == nil && !m.Enabled()) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return s.signToken(typMFAChallenge, s.cfg.MFA.ChallengeTTL, jwt.MapClaims{"sub": u.ID.String()})
+}
+
+// reauthenticate повторно проверяет пароль и второй фактор перед изменением 2FA.
+// Неудачи считаются по тому же ключу, что и при входе с кодом (CompleteMFALogin):
+// украденный access-токен не даёт перебирать коды.
+func (s *Service) reauthenticate(ctx context.Context, userID uuid.UUID, password, code string) (domain.UserMFA, error) {
+	throttle := []throttleKey{{key: "mfa:" + userID.String(), rule: s.cfg.LoginThrottle.Account}}
+	if err := s.checkThrottle(ctx, throttle); err != nil {
+		return domain.UserMFA{}, err
+	}
+
+	u, err := s.users.ByID(ctx, userID)
+	if err != nil {
+		return domain.UserMFA{}, domain.ErrInvalidCreds
+	}
+	// у пользователей, вошедших через провайдера, пароля может не быть — тогда достаточно второго фактора
+	if u.PasswordHash != "" && !s.checkPassword(ctx, u, password) {
+		s.recordFailure(ctx, throttle, &userID)
+		return domain.UserMFA{}, domain.ErrInvalidCreds
+	}
+	m, err := s.mfa.Get(ctx, userID)
+	if errors.Is(err, domain.ErrNotFound) || (err == nil && !m.Enabled()) {
+		return domain.UserMFA{}, domain.ErrMFANotEnabled
+	}
+	if err != nil {
+		return domain.UserMFA{}, err
+	}
+	if err := s.verifySecondFactor(ctx, m, code); err != nil {
+		if errors.Is(err, domain.ErrInvalidMFACode) {
+			s.recordFailure(ctx, throttle, &userID)
+		}
+		return domain.UserMFA{}, err
+	}
+	s.clearFailures(ctx, throttle[0].key)
+	return m, nil
+}
+
+// verifySecondFactor принимает TOTP-код (каждый не более одного раза) или код восстановления.
+func (s *Service) verifySecondFactor(ctx context.Context, m domain.UserMFA, code string) error {
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(m.TOTPSecret, code, time.Now(), s.cfg.MFA.Skew); ok {
+		fresh, err := s.mfa.UseStep(ctx, m.UserID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			// этот код уже использовали
+			return domain.ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := s.mfa.UseRecoveryCode(ctx, m.UserID, recoveryHash(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return domain.ErrInvalidMFACode
+	}
+	log.Info().Str("user_id", m.UserID.String()).Msg("mfa recovery code used")
+	s.recordEvent(ctx, domain.AuthEvent{UserID: &m.UserID, Type: domain.EventMFARecovery})
+	return nil
+}
+
+func (s *Service) replaceRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	n := s.cfg.MFA.RecoveryCodes
+	if n <= 0 {
+		n = 10
+	}
+	codes := make([]string, n)
+	hashes := make([][]byte, n)
+	for i := range codes {
+		c, err := randomRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = c
+		hashes[i] = recoveryHash(c)
+	}
+	if err := s.mfa.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// randomRecoveryCode — 50 бит в виде xxxxx-xxxxx (base32, без похожих 0/1/8).
+func randomRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	c := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+	return c[:5] + "-" + c[5:], nil
+}
+
+// recoveryHash нормализует ввод (регистр, дефисы, пробелы) и хэширует код.
+// Кодов с 50 битами энтропии достаточно, чтобы обойтись без bcrypt.
+func recoveryHash(code string) []byte {
+	norm := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+	return sha256sum(norm)
+}
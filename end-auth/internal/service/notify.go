@@ -0,0 +1,85 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"auth/internal/domain"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// Письмо <name> описывается парой шаблонов: <name>.txt.tmpl (с блоком "subject")
+// и необязательным <name>.html.tmpl. Текстовые шаблоны разбираются по отдельности:
+// в общем наборе блок "subject" из последнего файла перекрыл бы остальные.
+var (
+	textTemplates = parseTextTemplates()
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "templates/*.html.tmpl"))
+)
+
+func parseTextTemplates() map[string]*texttemplate.Template {
+	paths, err := fs.Glob(templateFS, "templates/*.txt.tmpl")
+	if err != nil {
+		panic(err)
+	}
+	out := make(map[string]*texttemplate.Template, len(paths))
+	for _, p := range paths {
+		out[strings.TrimSuffix(path.Base(p), ".txt.tmpl")] = texttemplate.Must(texttemplate.ParseFS(templateFS, p))
+	}
+	return out
+}
+
+const tmplPasswordReset = "password_reset"
+
+// renderMail собирает письмо из шаблона name.
+func renderMail(name, to string, data any) (domain.Mail, error) {
+	m := domain.Mail{To: to}
+
+	txt := textTemplates[name]
+	if txt == nil {
+		return m, fmt.Errorf("mail template %q not found", name)
+	}
+	var subj, body bytes.Buffer
+	if err := txt.ExecuteTemplate(&subj, "subject", data); err != nil {
+		return m, err
+	}
+	if err := txt.Execute(&body, data); err != nil {
+		return m, err
+	}
+	m.Subject = strings.TrimSpace(subj.String())
+	m.Text = body.String()
+
+	if h := htmlTemplates.Lookup(name + ".html.tmpl"); h != nil {
+		var html bytes.Buffer
+		if err := h.Execute(&html, data); err != nil {
+			return m, err
+		}
+		m.HTML = html.String()
+	}
+	return m, nil
+}
+
+func (s *Service) sendMail(ctx context.Context, name, to string, data any) error {
+	m, err := renderMail(name, to, data)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, m)
+}
+
+// humanTTL печатает срок действия кода для письма: «15 мин», «2 ч».
+func humanTTL(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		return fmt.Sprintf("%d ч", int(d/time.Hour))
+	}
+	return fmt.Sprintf("%d мин", int(d.Round(time.Minute)/time.Minute))
+}
@@ -0,0 +1,422 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"auth/internal/domain"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Типы токенов в ответах /oauth/* (RFC 7662, RFC 7009).
+const (
+	TokenTypeAccess  = "access_token"
+	TokenTypeRefresh = "refresh_token"
+)
+
+// Introspection — состояние токена по RFC 7662. Для неактивного токена заполнено только Active.
+type Introspection struct {
+	Active    bool
+	Subject   string
+	TokenType string
+	Scope     string
+	ClientID  string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// ServiceToken — ответ client_credentials.
+type ServiceToken struct {
+	AccessToken string
+	ExpiresIn   time.Duration
+	Scopes      []string
+}
+
+// ServiceInfo — то, что известно о владельце валидного токена сервиса.
+type ServiceInfo struct {
+	ClientID  string
+	Scopes    []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// ClientRegistration — параметры нового клиента OAuth.
+type ClientRegistration struct {
+	Name   string
+	Scopes []string
+	// RedirectURIs нужны клиентам, которые получают доступ от имени пользователя.
+	RedirectURIs []string
+	// FirstParty — наше приложение, согласие пользователя не спрашивается.
+	FirstParty bool
+	// Public — клиент без секрета (мобильное или браузерное приложение):
+	// только authorization code с PKCE.
+	Public bool
+}
+
+// CreateClient регистрирует клиента OAuth. Секрет возвращается один раз; у публичного клиента его нет.
+func (s *Service) CreateClient(ctx context.Context, reg ClientRegistration) (domain.OAuthClient, string, error) {
+	name := strings.TrimSpace(reg.Name)
+	if name == "" {
+		return domain.OAuthClient{}, "", errors.New("client name is required")
+	}
+	for _, sc := range reg.Scopes {
+		if !slices.Contains(domain.Scopes, sc) {
+			return domain.OAuthClient{}, "", fmt.Errorf("%w: %s", domain.ErrInvalidScope, sc)
+		}
+	}
+	for _, uri := range reg.RedirectURIs {
+		if !validRedirectURI(uri) {
+			return domain.OAuthClient{}, "", fmt.Errorf("%w: %s", domain.ErrInvalidRedirect, uri)
+		}
+	}
+	if reg.Public && len(reg.RedirectURIs) == 0 {
+		return domain.OAuthClient{}, "", errors.New("public client requires redirect uris")
+	}
+
+	id, err := randomString(16)
+	if err != nil {
+		return domain.OAuthClient{}, "", err
+	}
+	var secret string
+	var secretHash []byte
+	if !reg.Public {
+		if secret, err = randomString(32); err != nil {
+			return domain.OAuthClient{}, "", err
+		}
+		secretHash = sha256sum(secret)
+	}
+	c, err := s.clients.Create(ctx, domain.OAuthClient{
+		ID:           id,
+		Name:         name,
+		SecretHash:   secretHash,
+		Scopes:       nonNilScopes(reg.Scopes),
+		RedirectURIs: nonNilScopes(reg.RedirectURIs),
+		FirstParty:   reg.FirstParty,
+	})
+	if err != nil {
+		return domain.OAuthClient{}, "", err
+	}
+	return c, secret, nil
+}
+
+func (s *Service) ListClients(ctx context.Context) ([]domain.OAuthClient, error) {
+	return s.clients.List(ctx)
+}
+
+// DisableClient отключает клиента и завершает сессии, выданные ему пользователями.
+// Их access-токены перестают приниматься в пределах SessionCacheTTL.
+func (s *Service) DisableClient(ctx context.Context, id string) error {
+	ok, err := s.clients.Disable(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return domain.ErrNotFound
+	}
+	return s.refresh.RevokeForClient(ctx, id)
+}
+
+// AuthenticateClient проверяет client_id и секрет; при любой ошибке — ErrInvalidClient.
+// Публичный клиент предъявляет только client_id: его код авторизации защищён PKCE.
+func (s *Service) AuthenticateClient(ctx context.Context, id, secret string) (domain.OAuthClient, error) {
+	if id == "" {
+		return domain.OAuthClient{}, domain.ErrInvalidClient
+	}
+	c, err := s.clients.ByID(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.OAuthClient{}, domain.ErrInvalidClient
+	}
+	if err != nil {
+		return domain.OAuthClient{}, err
+	}
+	if c.DisabledAt != nil {
+		return domain.OAuthClient{}, domain.ErrInvalidClient
+	}
+	if c.Public() {
+		if secret != "" {
+			return domain.OAuthClient{}, domain.ErrInvalidClient
+		}
+		return c, nil
+	}
+	if secret == "" || subtle.ConstantTimeCompare(c.SecretHash, sha256sum(secret)) != 1 {
+		return domain.OAuthClient{}, domain.ErrInvalidClient
+	}
+	return c, nil
+}
+
+// IssueClientToken выпускает токен сервиса (grant_type=client_credentials). scope —
+// запрошенные права через пробел; пустой — все права клиента.
+func (s *Service) IssueClientToken(ctx context.Context, client domain.OAuthClient, scope string) (ServiceToken, error) {
+	if client.Public() {
+		return ServiceToken{}, domain.ErrUnauthorizedClient
+	}
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+	for _, sc := range scopes {
+		if !slices.Contains(client.Scopes, sc) {
+			return ServiceToken{}, domain.ErrInvalidScope
+		}
+	}
+	scopes = nonNilScopes(slices.Compact(slices.Sorted(slices.Values(scopes))))
+
+	ttl := s.cfg.serviceTokenTTL()
+	token, err := s.signToken(typService, ttl, jwt.MapClaims{
+		"sub":       client.ID,
+		"client_id": client.ID,
+		"scope":     strings.Join(scopes, " "),
+	})
+	if err != nil {
+		return ServiceToken{}, err
+	}
+	log.Debug().Str("client_id", client.ID).Strs("scopes", scopes).Msg("service token issued")
+	return ServiceToken{AccessToken: token, ExpiresIn: ttl, Scopes: scopes}, nil
+}
+
+// ValidateService проверяет токен сервиса: подпись, отзыв jti и то, что клиент не отключён.
+func (s *Service) ValidateService(ctx context.Context, token string) (ServiceInfo, error) {
+	claims, err := s.parseToken(token, typService)
+	if err != nil {
+		return ServiceInfo{}, err
+	}
+	clientID, _ := claims["client_id"].(string)
+	c, err := s.clients.ByID(ctx, clientID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return ServiceInfo{}, domain.ErrInvalidCreds
+	}
+	if err != nil {
+		return ServiceInfo{}, err
+	}
+	if c.DisabledAt != nil {
+		return ServiceInfo{}, domain.ErrInvalidCreds
+	}
+	revoked, err := s.accessRevoked(ctx, claims, nil)
+	if err != nil {
+		return ServiceInfo{}, err
+	}
+	if revoked {
+		return ServiceInfo{}, domain.ErrInvalidCreds
+	}
+
+	scope, _ := claims["scope"].(string)
+	info := ServiceInfo{ClientID: c.ID, Scopes: nonNilScopes(strings.Fields(scope))}
+	if iat, _ := claims.GetIssuedAt(); iat != nil {
+		info.IssuedAt = iat.Time
+	}
+	if exp, _ := claims.GetExpirationTime(); exp != nil {
+		info.ExpiresAt = exp.Time
+	}
+	return info, nil
+}
+
+// Introspect сообщает, действует ли токен. Тип определяется по виду токена
+// (access — JWT, персональный — с префиксом enp_, refresh — случайная строка),
+// поэтому token_type_hint не нужен.
+func (s *Service) Introspect(ctx context.Context, token string) (Introspection, error) {
+	if token == "" {
+		return Introspection{}, nil
+	}
+	if isPersonalToken(token) {
+		info, err := s.ValidatePersonalToken(ctx, token)
+		if errors.Is(err, domain.ErrInvalidCreds) {
+			return Introspection{}, nil
+		}
+		if err != nil {
+			return Introspection{}, err
+		}
+		return Introspection{
+			Active:    true,
+			Subject:   info.UserID.String(),
+			TokenType: TokenTypeAccess,
+			Scope:     strings.Join(info.Scopes, " "),
+			IssuedAt:  info.IssuedAt,
+			ExpiresAt: info.ExpiresAt,
+		}, nil
+	}
+	if isJWT(token) {
+		info, err := s.ValidateAccess(ctx, token)
+		if errors.Is(err, domain.ErrInvalidCreds) {
+			return s.introspectService(ctx, token)
+		}
+		if err != nil {
+			return Introspection{}, err
+		}
+		return Introspection{
+			Active:    true,
+			Subject:   info.UserID.String(),
+			TokenType: TokenTypeAccess,
+			Scope:     strings.Join(info.Scopes, " "),
+			ClientID:  info.ClientID,
+			IssuedAt:  info.IssuedAt,
+			ExpiresAt: info.ExpiresAt,
+		}, nil
+	}
+
+	rs, err := s.refresh.ByHashActive(ctx, sha256sum(token), time.Now())
+	if err != nil {
+		return Introspection{}, nil
+	}
+	u, err := s.users.ByID(ctx, rs.UserID)
+	if err != nil || u.Blocked(time.Now()) {
+		return Introspection{}, nil
+	}
+	return Introspection{
+		Active:    true,
+		Subject:   rs.UserID.String(),
+		TokenType: TokenTypeRefresh,
+		Scope:     strings.Join(rs.Scopes, " "),
+		ClientID:  sessionClient(rs),
+		IssuedAt:  rs.CreatedAt,
+		ExpiresAt: rs.ExpiresAt,
+	}, nil
+}
+
+func (s *Service) introspectService(ctx context.Context, token string) (Introspection, error) {
+	info, err := s.ValidateService(ctx, token)
+	if err != nil {
+		// невалидный токен — обычный ответ active=false, а не ошибка
+		if errors.Is(err, domain.ErrInvalidCreds) {
+			return Introspection{}, nil
+		}
+		return Introspection{}, err
+	}
+	return Introspection{
+		Active:    true,
+		Subject:   info.ClientID,
+		TokenType: TokenTypeAccess,
+		Scope:     strings.Join(info.Scopes, " "),
+		ClientID:  info.ClientID,
+		IssuedAt:  info.IssuedAt,
+		ExpiresAt: info.ExpiresAt,
+	}, nil
+}
+
+// RevokeToken отзывает токен по запросу клиента (RFC 7009). Refresh-токен завершает
+// всю сессию устройства, а с ней и выпущенные для неё access-токены. Неизвестные
+// и уже недействительные токены не считаются ошибкой. Клиент отзывает только выданные
+// ему токены; любые — только наш (first_party) клиент с секретом.
+func (s *Service) RevokeToken(ctx context.Context, client domain.OAuthClient, token string) error {
+	if token == "" {
+		return nil
+	}
+	// публичный клиент не подтверждает себя секретом, поэтому флага first_party ему мало
+	revokeAny := client.FirstParty && !client.Public()
+	if !revokeAny && !s.issuedTo(ctx, token, client.ID) {
+		return nil
+	}
+	if isPersonalToken(token) {
+		t, err := s.pats.ByHashActive(ctx, sha256sum(token))
+		if err != nil {
+			return nil
+		}
+		return s.RevokePersonalToken(ctx, t.UserID, t.ID)
+	}
+	if isJWT(token) {
+		userID, err := s.revokeAccess(ctx, token)
+		if err != nil {
+			return err
+		}
+		if userID == uuid.Nil {
+			return s.revokeServiceToken(ctx, client, token)
+		}
+		s.recordEvent(ctx, domain.AuthEvent{
+			UserID:  &userID,
+			Type:    domain.EventTokenRevoked,
+			Details: map[string]any{"client_id": client.ID, "token_type": TokenTypeAccess},
+		})
+		return nil
+	}
+
+	rs, err := s.refresh.ByHashActive(ctx, sha256sum(token), time.Now())
+	if err != nil {
+		return nil
+	}
+	if err := s.refresh.RevokeFamily(ctx, rs.FamilyID); err != nil {
+		return err
+	}
+	s.sessions.forget(rs.FamilyID)
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID: &rs.UserID,
+		Type:   domain.EventTokenRevoked,
+		Details: map[string]any{
+			"client_id":  client.ID,
+			"token_type": TokenTypeRefresh,
+			"family_id":  rs.FamilyID,
+		},
+	})
+	return nil
+}
+
+// revokeServiceToken отзывает токен сервиса; клиент может отозвать только свои токены.
+func (s *Service) revokeServiceToken(ctx context.Context, client domain.OAuthClient, token string) error {
+	claims, err := s.parseToken(token, typService)
+	if err != nil {
+		return nil
+	}
+	jti, _ := claims["jti"].(string)
+	clientID, _ := claims["client_id"].(string)
+	exp, _ := claims.GetExpirationTime()
+	if jti == "" || clientID != client.ID || exp == nil {
+		return nil
+	}
+	_, _ = s.revoked.DeleteExpired(ctx)
+	return s.revoked.Revoke(ctx, jti, nil, exp.Time)
+}
+
+// issuedTo сообщает, что токен выдан клиенту clientID: от имени пользователя или как токен сервиса.
+func (s *Service) issuedTo(ctx context.Context, token, clientID string) bool {
+	if isPersonalToken(token) {
+		return false
+	}
+	if isJWT(token) {
+		claims, err := s.parseToken(token, typAccess)
+		if err != nil {
+			if claims, err = s.parseToken(token, typService); err != nil {
+				return false
+			}
+		}
+		id, _ := claims["client_id"].(string)
+		return id == clientID
+	}
+	rs, err := s.refresh.ByHashActive(ctx, sha256sum(token), time.Now())
+	return err == nil && sessionClient(rs) == clientID
+}
+
+// validRedirectURI: https, http только на loopback (RFC 8252) или собственная
+// схема нативного приложения вида com.example.app:/callback; без фрагмента.
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Fragment != "" {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return u.Host != ""
+	case "http":
+		h := u.Hostname()
+		return h == "localhost" || h == "127.0.0.1" || h == "::1"
+	default:
+		return strings.Contains(u.Scheme, ".")
+	}
+}
+
+func nonNilScopes(scopes []string) []string {
+	if scopes == nil {
+		return []string{}
+	}
+	return scopes
+}
+
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
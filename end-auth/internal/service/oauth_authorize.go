@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"auth/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// OAuthConfig — доступ сторонних приложений от имени пользователя (authorization code + PKCE).
+type OAuthConfig struct {
+	// ConsentURL — страница фронтенда с экраном согласия; GET /oauth/authorize
+	// перенаправляет туда браузер с исходными параметрами запроса.
+	ConsentURL string
+	// CodeTTL — сколько живёт код авторизации.
+	CodeTTL time.Duration
+}
+
+// AuthorizeRequest — параметры запроса авторизации (RFC 6749, 4.1.1; RFC 7636).
+type AuthorizeRequest struct {
+	ClientID            string
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// AuthorizePrompt — данные для экрана согласия.
+type AuthorizePrompt struct {
+	Client domain.OAuthClient
+	Scopes []string
+	// ConsentRequired — нужно спросить пользователя. false — приложение наше или
+	// пользователь уже согласился на эти права, запрос можно подтверждать сразу.
+	ConsentRequired bool
+}
+
+// AuthorizeError — ошибка запроса авторизации, о которой приложению сообщают
+// редиректом на его redirect_uri (RFC 6749, 4.1.2.1).
+type AuthorizeError struct {
+	Code       string
+	RedirectTo string
+}
+
+func (e *AuthorizeError) Error() string {
+	return "authorize: " + e.Code
+}
+
+// AppTokens — токены приложения, действующего от имени пользователя.
+type AppTokens struct {
+	TokenPair
+	ExpiresIn time.Duration
+	Scopes    []string
+}
+
+// pkceChallengeLen — длина S256-челленджа: SHA-256 в base64url без выравнивания.
+const pkceChallengeLen = 43
+
+// CheckAuthorizeClient проверяет клиента и redirect_uri. Пока они не проверены,
+// браузер нельзя отправлять на redirect_uri даже с ошибкой — это open redirect.
+func (s *Service) CheckAuthorizeClient(ctx context.Context, clientID, redirectURI string) (domain.OAuthClient, error) {
+	c, err := s.clients.ByID(ctx, clientID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.OAuthClient{}, domain.ErrInvalidClient
+	}
+	if err != nil {
+		return domain.OAuthClient{}, err
+	}
+	if c.DisabledAt != nil {
+		return domain.OAuthClient{}, domain.ErrInvalidClient
+	}
+	if redirectURI == "" || !slices.Contains(c.RedirectURIs, redirectURI) {
+		return domain.OAuthClient{}, domain.ErrInvalidRedirect
+	}
+	return c, nil
+}
+
+// ConsentRedirect возвращает адрес экрана согласия, куда GET /oauth/authorize
+// отправляет браузер с теми же параметрами.
+func (s *Service) ConsentRedirect(ctx context.Context, clientID, redirectURI, rawQuery string) (string, error) {
+	if _, err := s.CheckAuthorizeClient(ctx, clientID, redirectURI); err != nil {
+		return "", err
+	}
+	u, err := url.Parse(s.cfg.OAuth.ConsentURL)
+	if err != nil || s.cfg.OAuth.ConsentURL == "" {
+		return "", errors.New("oauth consent url is not configured")
+	}
+	u.RawQuery = rawQuery
+	return u.String(), nil
+}
+
+// PrepareAuthorization проверяет запрос авторизации и сообщает, что показать пользователю.
+func (s *Service) PrepareAuthorization(ctx context.Context, userID uuid.UUID, req AuthorizeRequest) (AuthorizePrompt, error) {
+	c, scopes, err := s.checkAuthorizeRequest(ctx, req)
+	if err != nil {
+		return AuthorizePrompt{}, err
+	}
+	required, err := s.consentRequired(ctx, userID, c, scopes)
+	if err != nil {
+		return AuthorizePrompt{}, err
+	}
+	return AuthorizePrompt{Client: c, Scopes: scopes, ConsentRequired: required}, nil
+}
+
+// Authorize завершает запрос авторизации решением пользователя и возвращает адрес
+// приложения: с кодом авторизации или с error=access_denied.
+func (s *Service) Authorize(ctx context.Context, userID uuid.UUID, req AuthorizeRequest, approved bool) (string, error) {
+	c, scopes, err := s.checkAuthorizeRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	if !approved {
+		return authorizeRedirect(req.RedirectURI, req.State, "error", "access_denied"), nil
+	}
+
+	required, err := s.consentRequired(ctx, userID, c, scopes)
+	if err != nil {
+		return "", err
+	}
+	if required {
+		if err := s.consents.Grant(ctx, userID, c.ID, scopes); err != nil {
+			return "", err
+		}
+		s.recordEvent(ctx, domain.AuthEvent{
+			UserID:  &userID,
+			Type:    domain.EventOAuthConsent,
+			Details: map[string]any{"client_id": c.ID, "scopes": scopes},
+		})
+	}
+
+	code, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+	ttl := s.cfg.OAuth.CodeTTL
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	_, _ = s.codes.DeleteExpired(ctx)
+	err = s.codes.Create(ctx, domain.AuthorizationCode{
+		CodeHash:      sha256sum(code),
+		ClientID:      c.ID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return authorizeRedirect(req.RedirectURI, req.State, "code", code), nil
+}
+
+// ExchangeAuthorizationCode меняет код авторизации на токены (grant_type=authorization_code).
+// Повторное предъявление кода отзывает сессию, выпущенную по нему: код мог утечь.
+func (s *Service) ExchangeAuthorizationCode(ctx context.Context, app domain.OAuthClient, code, redirectURI, verifier string, client ClientInfo) (AppTokens, error) {
+	if code == "" || verifier == "" {
+		return AppTokens{}, domain.ErrInvalidGrant
+	}
+	hash := sha256sum(code)
+	ac, err := s.codes.Consume(ctx, hash)
+	if errors.Is(err, domain.ErrNotFound) {
+		s.revokeReusedCode(ctx, hash)
+		return AppTokens{}, domain.ErrInvalidGrant
+	}
+	if err != nil {
+		return AppTokens{}, err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	if ac.ClientID != app.ID || ac.RedirectURI != redirectURI ||
+		subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(challenge[:])), []byte(ac.CodeChallenge)) != 1 {
+		return AppTokens{}, domain.ErrInvalidGrant
+	}
+	u, err := s.users.ByID(ctx, ac.UserID)
+	if err != nil || u.Blocked(time.Now()) {
+		return AppTokens{}, domain.ErrInvalidGrant
+	}
+
+	rs := domain.RefreshSession{
+		UserID:     u.ID,
+		FamilyID:   uuid.New(),
+		ClientID:   &app.ID,
+		Scopes:     nonNilScopes(ac.Scopes),
+		UserAgent:  optString(client.UserAgent),
+		IP:         optString(client.IP),
+		DeviceName: optString(app.Name),
+	}
+	tp, err := s.startSession(ctx, u, rs)
+	if err != nil {
+		return AppTokens{}, err
+	}
+	if err := s.codes.SetFamily(ctx, hash, rs.FamilyID); err != nil {
+		return AppTokens{}, err
+	}
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  &u.ID,
+		Type:    domain.EventLoginSuccess,
+		Details: map[string]any{"method": "oauth", "client_id": app.ID},
+	})
+	return s.appTokens(tp, rs.Scopes), nil
+}
+
+// RefreshAppToken ротирует refresh-токен приложения (grant_type=refresh_token).
+// Права сессии не меняются; токен другого клиента не принимается.
+func (s *Service) RefreshAppToken(ctx context.Context, app domain.OAuthClient, refreshToken string, client ClientInfo) (AppTokens, error) {
+	tp, rs, err := s.rotateRefresh(ctx, refreshToken, app.ID, client)
+	if err != nil {
+		return AppTokens{}, err
+	}
+	return s.appTokens(tp, rs.Scopes), nil
+}
+
+// ListAuthorizedApps возвращает сторонние приложения, которым пользователь выдал доступ.
+func (s *Service) ListAuthorizedApps(ctx context.Context, userID uuid.UUID) ([]domain.OAuthConsent, error) {
+	return s.consents.ListByUser(ctx, userID)
+}
+
+// RevokeAuthorizedApp отзывает доступ приложения: согласие и все его сессии у пользователя.
+func (s *Service) RevokeAuthorizedApp(ctx context.Context, userID uuid.UUID, clientID string) error {
+	deleted, err := s.consents.Delete(ctx, userID, clientID)
+	if err != nil {
+		return err
+	}
+	families, err := s.refresh.RevokeForUserClient(ctx, userID, clientID)
+	if err != nil {
+		return err
+	}
+	if !deleted && len(families) == 0 {
+		return domain.ErrNotFound
+	}
+	for _, f := range families {
+		s.sessions.forget(f)
+	}
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  &userID,
+		Type:    domain.EventOAuthAppRevoked,
+		Details: map[string]any{"client_id": clientID},
+	})
+	return nil
+}
+
+// checkAuthorizeRequest проверяет запрос авторизации и возвращает запрошенные права.
+// Ошибки после проверки redirect_uri — *AuthorizeError.
+func (s *Service) checkAuthorizeRequest(ctx context.Context, req AuthorizeRequest) (domain.OAuthClient, []string, error) {
+	c, err := s.CheckAuthorizeClient(ctx, req.ClientID, req.RedirectURI)
+	if err != nil {
+		return domain.OAuthClient{}, nil, err
+	}
+	fail := func(code string) error {
+		return &AuthorizeError{Code: code, RedirectTo: authorizeRedirect(req.RedirectURI, req.State, "error", code)}
+	}
+
+	if req.ResponseType != "code" {
+		return c, nil, fail("unsupported_response_type")
+	}
+	// PKCE обязателен для всех клиентов и только S256
+	if req.CodeChallengeMethod != "S256" || len(req.CodeChallenge) != pkceChallengeLen {
+		return c, nil, fail("invalid_request")
+	}
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		scopes = c.Scopes
+	}
+	if len(scopes) == 0 {
+		return c, nil, fail("invalid_scope")
+	}
+	for _, sc := range scopes {
+		if !slices.Contains(c.Scopes, sc) {
+			return c, nil, fail("invalid_scope")
+		}
+	}
+	return c, slices.Compact(slices.Sorted(slices.Values(scopes))), nil
+}
+
+// consentRequired — нужно ли спрашивать пользователя. Нашим приложениям согласие не нужно.
+func (s *Service) consentRequired(ctx context.Context, userID uuid.UUID, c domain.OAuthClient, scopes []string) (bool, error) {
+	if c.FirstParty {
+		return false, nil
+	}
+	consent, err := s.consents.Get(ctx, userID, c.ID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, sc := range scopes {
+		if !slices.Contains(consent.Scopes, sc) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Service) revokeReusedCode(ctx context.Context, hash []byte) {
+	ac, err := s.codes.ByHash(ctx, hash)
+	if err != nil || ac.ConsumedAt == nil || ac.FamilyID == nil {
+		return
+	}
+	log.Warn().
+		Str("user_id", ac.UserID.String()).
+		Str("client_id", ac.ClientID).
+		Str("family_id", ac.FamilyID.String()).
+		Msg("authorization code reused, revoking issued session")
+
+	_ = s.refresh.RevokeFamily(ctx, *ac.FamilyID)
+	s.sessions.forget(*ac.FamilyID)
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  &ac.UserID,
+		Type:    domain.EventOAuthCodeReuse,
+		Details: map[string]any{"client_id": ac.ClientID, "family_id": *ac.FamilyID},
+	})
+}
+
+func (s *Service) appTokens(tp TokenPair, scopes []string) AppTokens {
+	return AppTokens{TokenPair: tp, ExpiresIn: s.cfg.AccessTTL, Scopes: nonNilScopes(scopes)}
+}
+
+// sessionClient — приложение, которому выдана сессия; пустая строка — обычный вход.
+func sessionClient(rs domain.RefreshSession) string {
+	if rs.ClientID == nil {
+		return ""
+	}
+	return *rs.ClientID
+}
+
+// authorizeRedirect добавляет к redirect_uri приложения параметры ответа и state.
+func authorizeRedirect(redirectURI, state, key, value string) string {
+	out := withParam(redirectURI, key, value)
+	if state != "" {
+		out = withParam(out, "state", state)
+	}
+	return out
+}
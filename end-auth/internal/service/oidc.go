@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"auth/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// OIDCConfig — вход через внешних OIDC-провайдеров. Сами провайдеры описываются в секции oidc.
+type OIDCConfig struct {
+	// PublicURL — внешний адрес auth; redirect_uri = <PublicURL>/api/v1/oidc/<provider>/callback.
+	PublicURL string
+	// ReturnTo — страница фронтенда, куда браузер вернётся с login_code или error.
+	ReturnTo string
+	// AllowedReturnTo — адреса, которые клиент может передать в return_to: схема, хост
+	// и порт должны совпадать точно, путь — начинаться с тех же сегментов.
+	AllowedReturnTo []string
+	// RequestTTL — сколько ждём возврата пользователя от провайдера.
+	RequestTTL time.Duration
+	// LoginCodeTTL — сколько живёт одноразовый login_code для обмена на токены.
+	LoginCodeTTL time.Duration
+}
+
+// Коды ошибок, с которыми браузер возвращается на ReturnTo.
+const (
+	oidcErrInvalidState  = "invalid_state"
+	oidcErrDenied        = "access_denied"
+	oidcErrProvider      = "provider_error"
+	oidcErrEmailRequired = "email_not_verified"
+	oidcErrConflict      = "account_exists"
+	oidcErrBlocked       = "blocked"
+	oidcErrInternal      = "internal"
+)
+
+// OIDCProviders возвращает имена настроенных провайдеров.
+func (s *Service) OIDCProviders() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OIDCStart — куда отправить браузер и cookie, которая привязывает вход к этому браузеру.
+type OIDCStart struct {
+	RedirectURL string
+	// StateHash кладётся в cookie на CookiePath (путь callback) до ExpiresAt; без неё
+	// callback отклоняется, иначе чужой state можно подсунуть жертве (login CSRF).
+	StateHash  string
+	CookiePath string
+	ExpiresAt  time.Time
+	// Secure — PublicURL на https.
+	Secure bool
+}
+
+// StartOIDC начинает вход через провайдера.
+func (s *Service) StartOIDC(ctx context.Context, provider, returnTo string) (OIDCStart, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return OIDCStart{}, domain.ErrUnknownProvider
+	}
+	returnTo, err := s.resolveReturnTo(returnTo)
+	if err != nil {
+		return OIDCStart{}, err
+	}
+
+	state, err := randomString(32)
+	if err != nil {
+		return OIDCStart{}, err
+	}
+	nonce, err := randomString(32)
+	if err != nil {
+		return OIDCStart{}, err
+	}
+	verifier, err := randomString(32)
+	if err != nil {
+		return OIDCStart{}, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	redirectURI := s.oidcRedirectURI(provider)
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, base64.RawURLEncoding.EncodeToString(challenge[:]), redirectURI)
+	if err != nil {
+		return OIDCStart{}, err
+	}
+	callback, err := url.Parse(redirectURI)
+	if err != nil {
+		return OIDCStart{}, err
+	}
+	expiresAt := time.Now().Add(s.cfg.OIDC.RequestTTL)
+
+	_, _ = s.oidcRequests.DeleteExpired(ctx)
+	err = s.oidcRequests.Create(ctx, domain.OIDCRequest{
+		State:        state,
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ReturnTo:     returnTo,
+		ExpiresAt:    expiresAt,
+	})
+	if err != nil {
+		return OIDCStart{}, err
+	}
+	return OIDCStart{
+		RedirectURL: authURL,
+		StateHash:   oidcStateHash(state),
+		CookiePath:  callback.EscapedPath(),
+		ExpiresAt:   expiresAt,
+		Secure:      callback.Scheme == "https",
+	}, nil
+}
+
+// OIDCCallback обрабатывает возврат от провайдера и всегда возвращает адрес фронтенда:
+// с login_code при успехе или с error. stateHash — значение cookie из StartOIDC.
+// Ошибка возвращается только для логирования.
+func (s *Service) OIDCCallback(ctx context.Context, provider, state, stateHash, code, providerError string) (string, error) {
+	// state не расходуем: запрос мог прийти из чужого браузера, настоящий ещё вернётся
+	if state == "" || subtle.ConstantTimeCompare([]byte(oidcStateHash(state)), []byte(stateHash)) != 1 {
+		return withError(s.cfg.OIDC.ReturnTo, oidcErrInvalidState), errors.New("state does not match browser cookie")
+	}
+	req, err := s.oidcRequests.ConsumeState(ctx, state, provider)
+	if err != nil {
+		return withError(s.cfg.OIDC.ReturnTo, oidcErrInvalidState), err
+	}
+	if providerError != "" {
+		return withError(req.ReturnTo, oidcErrDenied), errors.New("provider returned error: " + providerError)
+	}
+	p, ok := s.providers[provider]
+	if !ok {
+		return withError(req.ReturnTo, oidcErrProvider), domain.ErrUnknownProvider
+	}
+
+	ext, err := p.Exchange(ctx, code, req.CodeVerifier, s.oidcRedirectURI(provider), req.Nonce)
+	if err != nil {
+		return withError(req.ReturnTo, oidcErrProvider), err
+	}
+
+	u, err := s.resolveIdentity(ctx, p, ext)
+	switch {
+	case errors.Is(err, domain.ErrEmailNotVerified):
+		return withError(req.ReturnTo, oidcErrEmailRequired), err
+	case errors.Is(err, domain.ErrIdentityConflict):
+		return withError(req.ReturnTo, oidcErrConflict), err
+	case err != nil:
+		return withError(req.ReturnTo, oidcErrInternal), err
+	}
+	if err := checkBlocked(u); err != nil {
+		return withError(req.ReturnTo, oidcErrBlocked), err
+	}
+
+	loginCode, err := randomString(32)
+	if err != nil {
+		return withError(req.ReturnTo, oidcErrInternal), err
+	}
+	if err := s.oidcRequests.SetLoginCode(ctx, req.State, u.ID, sha256sum(loginCode), time.Now().Add(s.cfg.OIDC.LoginCodeTTL)); err != nil {
+		return withError(req.ReturnTo, oidcErrInternal), err
+	}
+	return withParam(req.ReturnTo, "login_code", loginCode), nil
+}
+
+// ExchangeOIDCLogin меняет одноразовый login_code на токены (или MFA-челлендж).
+func (s *Service) ExchangeOIDCLogin(ctx context.Context, loginCode string, client ClientInfo) (LoginResult, error) {
+	if loginCode == "" {
+		return LoginResult{}, domain.ErrInvalidToken
+	}
+	req, err := s.oidcRequests.ConsumeLoginCode(ctx, sha256sum(loginCode))
+	if err != nil || req.UserID == nil {
+		return LoginResult{}, domain.ErrInvalidToken
+	}
+	u, err := s.users.ByID(ctx, *req.UserID)
+	if err != nil {
+		return LoginResult{}, domain.ErrInvalidToken
+	}
+	if err := checkBlocked(u); err != nil {
+		return LoginResult{}, err
+	}
+	return s.finishLogin(ctx, u, "oidc", client)
+}
+
+func (s *Service) ListIdentities(ctx context.Context, userID uuid.UUID) ([]domain.Identity, error) {
+	return s.identities.ListByUser(ctx, userID)
+}
+
+// resolveIdentity находит пользователя по внешнему аккаунту, при первом входе
+// привязывает его к существующему пользователю с тем же email или создаёт нового.
+// Привязка и создание требуют email, подтверждённого провайдером; к аккаунту с
+// неподтверждённым email не привязываем — его мог заранее зарегистрировать кто-то другой.
+func (s *Service) resolveIdentity(ctx context.Context, p domain.IdentityProvider, ext domain.ExternalIdentity) (domain.User, error) {
+	id, err := s.identities.ByProviderSubject(ctx, ext.Provider, ext.Subject)
+	if err == nil {
+		_ = s.identities.Touch(ctx, id.ID, optString(ext.Email))
+		return s.users.ByID(ctx, id.UserID)
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return domain.User{}, err
+	}
+
+	if ext.Email == "" || !ext.EmailVerified {
+		return domain.User{}, domain.ErrEmailNotVerified
+	}
+	email := normEmail(ext.Email)
+	u, err := s.users.ByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if !p.TrustEmail() || u.EmailVerifiedAt == nil {
+			return domain.User{}, domain.ErrIdentityConflict
+		}
+	case errors.Is(err, domain.ErrNotFound):
+		// пароля у такого пользователя нет, пока он не задаст его через сброс
+		u, err = s.users.Create(ctx, email, "")
+		if errors.Is(err, domain.ErrAlreadyExists) {
+			return domain.User{}, domain.ErrIdentityConflict
+		}
+		if err != nil {
+			return domain.User{}, err
+		}
+		if _, err := s.users.MarkEmailVerified(ctx, u.ID, email); err != nil {
+			return domain.User{}, err
+		}
+		now := time.Now()
+		u.EmailVerifiedAt = &now
+	default:
+		return domain.User{}, err
+	}
+
+	if _, err := s.identities.Create(ctx, domain.Identity{
+		UserID:   u.ID,
+		Provider: ext.Provider,
+		Subject:  ext.Subject,
+		Email:    optString(email),
+	}); err != nil {
+		return domain.User{}, err
+	}
+	log.Info().Str("user_id", u.ID.String()).Str("provider", ext.Provider).Msg("external identity linked")
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  &u.ID,
+		Type:    domain.EventOIDCLinked,
+		Details: map[string]any{"provider": ext.Provider},
+	})
+	return u, nil
+}
+
+func (s *Service) oidcRedirectURI(provider string) string {
+	return strings.TrimRight(s.cfg.OIDC.PublicURL, "/") + "/api/v1/oidc/" + url.PathEscape(provider) + "/callback"
+}
+
+func oidcStateHash(state string) string {
+	return base64.RawURLEncoding.EncodeToString(sha256sum(state))
+}
+
+// resolveReturnTo проверяет, что return_to ведёт на разрешённую страницу, иначе — open redirect.
+func (s *Service) resolveReturnTo(returnTo string) (string, error) {
+	if returnTo == "" || returnTo == s.cfg.OIDC.ReturnTo {
+		return s.cfg.OIDC.ReturnTo, nil
+	}
+	u, err := url.Parse(returnTo)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || u.User != nil {
+		return "", domain.ErrInvalidRedirect
+	}
+	for _, allowed := range s.cfg.OIDC.AllowedReturnTo {
+		a, err := url.Parse(allowed)
+		if err != nil || a.Host == "" {
+			continue
+		}
+		if sameOrigin(u, a) && pathWithin(u.Path, a.Path) {
+			return returnTo, nil
+		}
+	}
+	return "", domain.ErrInvalidRedirect
+}
+
+// sameOrigin сравнивает схему, хост и порт; порт по умолчанию равен явно указанному.
+func sameOrigin(a, b *url.URL) bool {
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Hostname(), b.Hostname()) &&
+		effectivePort(a) == effectivePort(b)
+}
+
+func effectivePort(u *url.URL) string {
+	if p := u.Port(); p != "" {
+		return p
+	}
+	if strings.EqualFold(u.Scheme, "https") {
+		return "443"
+	}
+	return "80"
+}
+
+// pathWithin: /app/callback входит в /app, а /application и /app/../admin — нет.
+func pathWithin(p, base string) bool {
+	segs := strings.Split(strings.Trim(p, "/"), "/")
+	for _, seg := range segs {
+		if seg == "." || seg == ".." {
+			return false
+		}
+	}
+	baseSegs := strings.Split(strings.Trim(base, "/"), "/")
+	if len(baseSegs) == 1 && baseSegs[0] == "" {
+		return true
+	}
+	if len(segs) < len(baseSegs) {
+		return false
+	}
+	for i, seg := range baseSegs {
+		if segs[i] != seg {
+			return false
+		}
+	}
+	return true
+}
+
+func withError(returnTo, code string) string {
+	return withParam(returnTo, "error", code)
+}
+
+func withParam(rawURL, key, value string) string {
+	out, err := withQuery(rawURL, key, value)
+	if err != nil {
+		return rawURL
+	}
+	return out
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"auth/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// personalTokenPrefix отличает персональные токены от JWT и refresh-токенов
+// и помогает сканерам секретов находить их в коде.
+const personalTokenPrefix = "enp_"
+
+const maxPersonalTokenName = 100
+
+// PersonalTokenInfo — то, что известно о владельце валидного персонального токена.
+type PersonalTokenInfo struct {
+	TokenID       uuid.UUID
+	UserID        uuid.UUID
+	EmailVerified bool
+	Scopes        []string
+	IssuedAt      time.Time
+	ExpiresAt     time.Time
+}
+
+// CreatePersonalToken выпускает персональный токен с правами scopes до expiresAt.
+// Сам токен возвращается один раз, в базе хранится только его хэш.
+func (s *Service) CreatePersonalToken(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt time.Time) (domain.PersonalToken, string, error) {
+	name = truncateRunes(strings.TrimSpace(name), maxPersonalTokenName)
+	if len(scopes) == 0 {
+		return domain.PersonalToken{}, "", domain.ErrInvalidScope
+	}
+	for _, sc := range scopes {
+		if !slices.Contains(domain.Scopes, sc) {
+			return domain.PersonalToken{}, "", domain.ErrInvalidScope
+		}
+	}
+	maxTTL := s.cfg.PersonalTokenMaxTTL
+	if maxTTL <= 0 {
+		maxTTL = 365 * 24 * time.Hour
+	}
+	now := time.Now()
+	if !expiresAt.After(now) || expiresAt.After(now.Add(maxTTL)) {
+		return domain.PersonalToken{}, "", domain.ErrInvalidExpiry
+	}
+
+	secret, err := randomString(32)
+	if err != nil {
+		return domain.PersonalToken{}, "", err
+	}
+	raw := personalTokenPrefix + secret
+	t, err := s.pats.Create(ctx, domain.PersonalToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: sha256sum(raw),
+		Scopes:    slices.Compact(slices.Sorted(slices.Values(scopes))),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return domain.PersonalToken{}, "", err
+	}
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID: &userID,
+		Type:   domain.EventPATCreated,
+		Details: map[string]any{
+			"token_id": t.ID,
+			"name":     t.Name,
+			"scopes":   t.Scopes,
+		},
+	})
+	return t, raw, nil
+}
+
+func (s *Service) ListPersonalTokens(ctx context.Context, userID uuid.UUID) ([]domain.PersonalToken, error) {
+	return s.pats.ListByUser(ctx, userID)
+}
+
+func (s *Service) RevokePersonalToken(ctx context.Context, userID, tokenID uuid.UUID) error {
+	ok, err := s.pats.Revoke(ctx, tokenID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return domain.ErrNotFound
+	}
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  &userID,
+		Type:    domain.EventPATRevoked,
+		Details: map[string]any{"token_id": tokenID},
+	})
+	return nil
+}
+
+// ValidatePersonalToken проверяет персональный токен. Роли пользователя токену
+// не передаются: он даёт только права из scopes. Токен перестаёт действовать
+// вместе со всеми access-токенами (смена и сброс пароля, выход везде, действия
+// администратора) и пока пользователь обязан сменить пароль.
+func (s *Service) ValidatePersonalToken(ctx context.Context, token string) (PersonalTokenInfo, error) {
+	if !isPersonalToken(token) {
+		return PersonalTokenInfo{}, domain.ErrInvalidCreds
+	}
+	t, err := s.pats.ByHashActive(ctx, sha256sum(token))
+	if errors.Is(err, domain.ErrNotFound) {
+		return PersonalTokenInfo{}, domain.ErrInvalidCreds
+	}
+	if err != nil {
+		return PersonalTokenInfo{}, err
+	}
+	u, err := s.users.ByID(ctx, t.UserID)
+	if err != nil || u.Blocked(time.Now()) || u.MustResetPassword {
+		return PersonalTokenInfo{}, domain.ErrInvalidCreds
+	}
+	if u.TokensValidAfter != nil && !t.CreatedAt.After(*u.TokensValidAfter) {
+		return PersonalTokenInfo{}, domain.ErrInvalidCreds
+	}
+	_ = s.pats.Touch(ctx, t.ID)
+
+	return PersonalTokenInfo{
+		TokenID:       t.ID,
+		UserID:        u.ID,
+		EmailVerified: u.EmailVerifiedAt != nil,
+		Scopes:        nonNilScopes(t.Scopes),
+		IssuedAt:      t.CreatedAt,
+		ExpiresAt:     t.ExpiresAt,
+	}, nil
+}
+
+func isPersonalToken(token string) bool {
+	return strings.HasPrefix(token, personalTokenPrefix)
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
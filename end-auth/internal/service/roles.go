@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+
+	"auth/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+func (s *Service) ListRoles(ctx context.Context) ([]domain.Role, error) {
+	return s.roles.List(ctx)
+}
+
+func (s *Service) UserRoles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	if _, err := s.users.ByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.roles.ListByUser(ctx, userID)
+}
+
+// GrantRole выдаёт роль. actor — кто выдал (nil, если из authctl).
+// В уже выпущенных access-токенах роль появится после следующего refresh.
+func (s *Service) GrantRole(ctx context.Context, actor *uuid.UUID, userID uuid.UUID, role string) error {
+	if err := s.checkRole(ctx, role); err != nil {
+		return err
+	}
+	if _, err := s.users.ByID(ctx, userID); err != nil {
+		return err
+	}
+	granted, err := s.roles.Grant(ctx, userID, role, actor)
+	if err != nil || !granted {
+		return err
+	}
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  &userID,
+		ActorID: actor,
+		Type:    domain.EventRoleGranted,
+		Details: map[string]any{"role": role},
+	})
+	return nil
+}
+
+func (s *Service) RevokeRole(ctx context.Context, actor *uuid.UUID, userID uuid.UUID, role string) error {
+	if err := s.checkRole(ctx, role); err != nil {
+		return err
+	}
+	revoked, err := s.roles.Revoke(ctx, userID, role)
+	if err != nil || !revoked {
+		return err
+	}
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  &userID,
+		ActorID: actor,
+		Type:    domain.EventRoleRevoked,
+		Details: map[string]any{"role": role},
+	})
+	return nil
+}
+
+func (s *Service) checkRole(ctx context.Context, role string) error {
+	roles, err := s.roles.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range roles {
+		if r.Name == role {
+			return nil
+		}
+	}
+	return domain.ErrUnknownRole
+}
@@ -6,93 +6,336 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"math/big"
 	"strings"
 	"time"
 
 	"auth/internal/domain"
+	"auth/internal/keys"
+	"auth/internal/password"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/rs/zerolog/log"
 )
 
 type Config struct {
-	Issuer      string
-	JWTSecret   string
-	AccessTTL   time.Duration
-	RefreshTTL  time.Duration
-	ResetOTPTTL time.Duration
+	Issuer     string
+	Keyring    KeyringConfig
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	// ServiceTokenTTL — время жизни токенов сервисов (client_credentials).
+	ServiceTokenTTL time.Duration
+	// PersonalTokenMaxTTL — максимальный срок действия персонального токена.
+	PersonalTokenMaxTTL time.Duration
+	// SessionCacheTTL — сколько помнить результат проверки сессии (sid) access-токена; 0 — проверять каждый раз.
+	SessionCacheTTL time.Duration
+	ResetOTPTTL     time.Duration
+	// ResetOTPLength — число цифр в коде сброса.
+	ResetOTPLength int
+	// ResetOTPMaxAttempts — сколько неверных вводов выдерживает код, после чего он аннулируется.
+	ResetOTPMaxAttempts int
+	// ResetOTPCooldown — минимальный интервал между отправками кода одному пользователю.
+	ResetOTPCooldown  time.Duration
+	EmailVerification EmailVerificationConfig
+	LoginThrottle     LoginThrottleConfig
+	MFA               MFAConfig
+	OIDC              OIDCConfig
+	OAuth             OAuthConfig
+	MagicLink         MagicLinkConfig
+	// PasswordPolicy — требования к новым паролям.
+	PasswordPolicy password.PolicyConfig
+	// Password — параметры argon2id для новых хэшей паролей; хэши со старыми
+	// параметрами (и bcrypt) пересчитываются при следующем входе.
+	Password password.Params
+	// DevMode разрешает отдавать OTP-код прямо в ответе API. Только для локальной разработки.
+	DevMode bool
+}
+
+// KeyringConfig описывает каталог ключей подписи (см. `authctl keys`).
+// Если Dir не задан, при старте генерируется временный ключ (только для разработки).
+type KeyringConfig struct {
+	Dir            string
+	Retention      time.Duration
+	ReloadInterval time.Duration
+}
+
+// EmailVerificationConfig — подтверждение email после регистрации.
+type EmailVerificationConfig struct {
+	TTL time.Duration
+	// LinkURL — страница фронтенда из письма; токен передаётся в параметре token.
+	LinkURL string
+	// RequireForLogin не пускает неподтверждённые аккаунты: ни логин, ни токены при регистрации.
+	RequireForLogin bool
+	// RequireForPasswordReset не отправляет коды сброса на неподтверждённые адреса.
+	RequireForPasswordReset bool
+}
+
+// KeyRetention — сколько заменённый ключ остаётся в JWKS.
+// Не может быть меньше времени жизни самого долгоживущего токена, подписанного ключом:
+// кроме access-токенов это ссылки из писем, челлендж 2FA и токены сервисов.
+func (c Config) KeyRetention() time.Duration {
+	return max(c.Keyring.Retention, c.AccessTTL, c.EmailVerification.TTL, c.magicLinkTTL(), c.MFA.ChallengeTTL, c.serviceTokenTTL())
+}
+
+func (c Config) serviceTokenTTL() time.Duration {
+	if c.ServiceTokenTTL <= 0 {
+		return 5 * time.Minute
+	}
+	return c.ServiceTokenTTL
 }
 
 type Service struct {
-	users   domain.UserRepository
-	refresh domain.RefreshRepository
-	resets  domain.PasswordResetRepository
-	cfg     Config
+	users    domain.UserRepository
+	refresh  domain.RefreshRepository
+	revoked  domain.RevokedTokenRepository
+	resets   domain.PasswordResetRepository
+	events   domain.EventRepository
+	throttle domain.LoginThrottleRepository
+	mfa      domain.MFARepository
+	roles    domain.RoleRepository
+
+	identities   domain.IdentityRepository
+	oidcRequests domain.OIDCRequestRepository
+	providers    map[string]domain.IdentityProvider
+	clients      domain.OAuthClientRepository
+	codes        domain.AuthorizationCodeRepository
+	consents     domain.ConsentRepository
+	pats         domain.PersonalTokenRepository
+	magicLinks   domain.MagicLinkRepository
+
+	mailer   domain.Mailer
+	ring     *keys.Ring
+	sessions *sessionCache
+	hasher   password.Hasher
+	policy   *password.Policy
+	cfg      Config
 }
 
-func New(users domain.UserRepository, refresh domain.RefreshRepository, resets domain.PasswordResetRepository, cfg Config) *Service {
-	return &Service{users: users, refresh: refresh, resets: resets, cfg: cfg}
+func New(users domain.UserRepository, refresh domain.RefreshRepository, revoked domain.RevokedTokenRepository, resets domain.PasswordResetRepository, events domain.EventRepository, throttle domain.LoginThrottleRepository, mfa domain.MFARepository, roles domain.RoleRepository, identities domain.IdentityRepository, oidcRequests domain.OIDCRequestRepository, providers []domain.IdentityProvider, clients domain.OAuthClientRepository, codes domain.AuthorizationCodeRepository, consents domain.ConsentRepository, pats domain.PersonalTokenRepository, magicLinks domain.MagicLinkRepository, mailer domain.Mailer, ring *keys.Ring, policy *password.Policy, cfg Config) *Service {
+	if policy == nil {
+		// без корпуса утёкших паролей загрузка не может завершиться ошибкой
+		pc := cfg.PasswordPolicy
+		pc.BreachedFile = ""
+		policy, _ = password.NewPolicy(pc)
+	}
+	byName := make(map[string]domain.IdentityProvider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
+	return &Service{
+		users: users, refresh: refresh, revoked: revoked, resets: resets, events: events, throttle: throttle, mfa: mfa, roles: roles,
+		identities: identities, oidcRequests: oidcRequests, providers: byName, clients: clients, codes: codes, consents: consents, pats: pats, magicLinks: magicLinks,
+		mailer: mailer, ring: ring, sessions: newSessionCache(cfg.SessionCacheTTL),
+		hasher: password.NewArgon2id(cfg.Password), policy: policy, cfg: cfg,
+	}
 }
 
+const (
+	typAccess = "access"
+	// typService — токен сервиса (client_credentials), без пользователя.
+	typService     = "service"
+	typEmailVerify = "email_verify"
+	// typMFAChallenge — пароль проверен, ждём второй фактор.
+	typMFAChallenge = "mfa_challenge"
+	typMagicLink    = "magic_link"
+)
+
 type TokenPair struct {
 	AccessToken  string
 	RefreshToken string
 }
 
-func (s *Service) Register(ctx context.Context, email, password string) (TokenPair, error) {
+// ClientInfo — метаданные устройства, с которого пришёл запрос; сохраняются в refresh-сессии.
+type ClientInfo struct {
+	UserAgent  string
+	IP         string
+	DeviceName string
+}
+
+func (s *Service) Register(ctx context.Context, email, password string, client ClientInfo) (TokenPair, error) {
 	email = normEmail(email)
-	if err := validatePassword(password); err != nil {
+	if err := s.checkNewPassword(ctx, email, nil, password); err != nil {
 		return TokenPair{}, err
 	}
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := s.hasher.Hash(password)
 	if err != nil {
 		return TokenPair{}, err
 	}
-	u, err := s.users.Create(ctx, email, string(hash))
+	u, err := s.users.Create(ctx, email, hash)
 	if err != nil {
 		return TokenPair{}, err
 	}
-	return s.issuePair(ctx, u.ID)
+	s.recordEvent(ctx, domain.AuthEvent{UserID: &u.ID, Type: domain.EventRegistered})
+	if err := s.sendVerification(ctx, u); err != nil {
+		// пользователь уже создан; письмо можно запросить повторно
+		log.Error().Err(err).Str("user_id", u.ID.String()).Msg("failed to send email verification")
+	}
+	if s.cfg.EmailVerification.RequireForLogin {
+		return TokenPair{}, domain.ErrEmailNotVerified
+	}
+	return s.issuePair(ctx, u, nil, client)
 }
 
-func (s *Service) Login(ctx context.Context, email, password string) (TokenPair, error) {
+// LoginResult — либо пара токенов, либо, если у пользователя включена 2FA,
+// токен MFA-челленджа для CompleteMFALogin.
+type LoginResult struct {
+	Tokens       TokenPair
+	MFAChallenge string
+}
+
+// Login проверяет email/пароль. Неудачные попытки считаются по аккаунту и по IP;
+// после серии неудач вход временно закрывается (*domain.LockedError).
+func (s *Service) Login(ctx context.Context, email, password string, client ClientInfo) (LoginResult, error) {
 	email = normEmail(email)
+	throttle := s.loginKeys(email, client.IP)
+	if err := s.checkThrottle(ctx, throttle); err != nil {
+		s.loginFailed(ctx, nil, email, "locked")
+		return LoginResult{}, err
+	}
+
 	u, err := s.users.ByEmail(ctx, email)
 	if err != nil {
-		return TokenPair{}, domain.ErrInvalidCreds
+		s.recordFailure(ctx, throttle, nil)
+		s.loginFailed(ctx, nil, email, "unknown_email")
+		return LoginResult{}, domain.ErrInvalidCreds
 	}
-	if u.IsBlocked {
-		return TokenPair{}, domain.ErrBlockedUser
+	if !s.checkPassword(ctx, u, password) {
+		s.recordFailure(ctx, throttle, &u.ID)
+		s.loginFailed(ctx, &u.ID, "", "invalid_password")
+		return LoginResult{}, domain.ErrInvalidCreds
 	}
-	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
-		return TokenPair{}, domain.ErrInvalidCreds
+	s.clearFailures(ctx, throttle[0].key)
+	// причину и срок блокировки показываем только знающему пароль
+	if err := checkBlocked(u); err != nil {
+		s.loginFailed(ctx, &u.ID, "", "blocked")
+		return LoginResult{}, err
+	}
+	return s.finishLogin(ctx, u, "password", client)
+}
+
+// finishLogin — общая часть всех способов входа после проверки первого фактора;
+// method попадает в журнал (password, oidc…).
+func (s *Service) finishLogin(ctx context.Context, u domain.User, method string, client ClientInfo) (LoginResult, error) {
+	if u.MustResetPassword {
+		s.loginFailed(ctx, &u.ID, "", "password_reset_required")
+		return LoginResult{}, domain.ErrPasswordResetRequired
 	}
+	if s.cfg.EmailVerification.RequireForLogin && u.EmailVerifiedAt == nil {
+		s.loginFailed(ctx, &u.ID, "", "email_not_verified")
+		return LoginResult{}, domain.ErrEmailNotVerified
+	}
+
+	challenge, err := s.mfaChallenge(ctx, u)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	if challenge != "" {
+		return LoginResult{MFAChallenge: challenge}, nil
+	}
+
 	_ = s.users.SetLastLogin(ctx, u.ID, time.Now().UTC())
-	return s.issuePair(ctx, u.ID)
+	tp, err := s.issuePair(ctx, u, nil, client)
+	if err != nil {
+		return LoginResult{}, err
+	}
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  &u.ID,
+		Type:    domain.EventLoginSuccess,
+		Details: map[string]any{"method": method},
+	})
+	return LoginResult{Tokens: tp}, nil
+}
+
+// Refresh ротирует refresh-токен. Предъявление уже отозванного токена из цепочки
+// означает, что он утёк: отзываем всю семью сессий и пишем событие безопасности.
+// Токены сторонних приложений обновляются только через /oauth/token.
+func (s *Service) Refresh(ctx context.Context, refreshToken string, client ClientInfo) (TokenPair, error) {
+	tp, _, err := s.rotateRefresh(ctx, refreshToken, "", client)
+	return tp, err
 }
 
-func (s *Service) Refresh(ctx context.Context, refreshToken string) (TokenPair, error) {
+// rotateRefresh ротирует refresh-токен сессии, выпущенной клиенту clientID
+// (пустой — обычный вход пользователя), и возвращает заменённую сессию.
+func (s *Service) rotateRefresh(ctx context.Context, refreshToken, clientID string, client ClientInfo) (TokenPair, domain.RefreshSession, error) {
 	if refreshToken == "" {
-		return TokenPair{}, domain.ErrInvalidRefresh
+		return TokenPair{}, domain.RefreshSession{}, domain.ErrInvalidRefresh
 	}
-	rs, err := s.refresh.ByHashActive(ctx, sha256sum(refreshToken), time.Now())
+	rs, err := s.refresh.ByHash(ctx, sha256sum(refreshToken))
 	if err != nil {
-		return TokenPair{}, domain.ErrInvalidRefresh
+		return TokenPair{}, domain.RefreshSession{}, domain.ErrInvalidRefresh
+	}
+	if sessionClient(rs) != clientID {
+		return TokenPair{}, domain.RefreshSession{}, domain.ErrInvalidRefresh
 	}
+	if rs.RevokedAt != nil {
+		s.revokeReusedFamily(ctx, rs)
+		return TokenPair{}, domain.RefreshSession{}, domain.ErrRefreshReuse
+	}
+	if !time.Now().Before(rs.ExpiresAt) {
+		return TokenPair{}, domain.RefreshSession{}, domain.ErrInvalidRefresh
+	}
+
 	u, err := s.users.ByID(ctx, rs.UserID)
-	if err != nil || u.IsBlocked {
+	if err != nil {
 		_ = s.refresh.RevokeAllForUser(ctx, rs.UserID)
-		if err == nil && u.IsBlocked {
-			return TokenPair{}, domain.ErrBlockedUser
-		}
-		return TokenPair{}, domain.ErrInvalidRefresh
+		return TokenPair{}, domain.RefreshSession{}, domain.ErrInvalidRefresh
+	}
+	if err := checkBlocked(u); err != nil {
+		return TokenPair{}, domain.RefreshSession{}, err
+	}
+
+	rotated, err := s.refresh.Rotate(ctx, rs.ID)
+	if err != nil {
+		return TokenPair{}, domain.RefreshSession{}, err
+	}
+	if !rotated {
+		// параллельный запрос с тем же токеном успел ротировать его раньше
+		return TokenPair{}, domain.RefreshSession{}, domain.ErrInvalidRefresh
+	}
+	tp, err := s.issuePair(ctx, u, &rs, client)
+	if err != nil {
+		return TokenPair{}, domain.RefreshSession{}, err
+	}
+	details := map[string]any{"family_id": rs.FamilyID}
+	if rs.ClientID != nil {
+		details["client_id"] = *rs.ClientID
 	}
-	_ = s.refresh.RevokeByID(ctx, rs.ID) // rotation
-	return s.issuePair(ctx, rs.UserID)
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  &u.ID,
+		Type:    domain.EventRefresh,
+		Details: details,
+	})
+	return tp, rs, nil
 }
 
-func (s *Service) Logout(ctx context.Context, refreshToken string) error {
+func (s *Service) revokeReusedFamily(ctx context.Context, rs domain.RefreshSession) {
+	log.Warn().
+		Str("user_id", rs.UserID.String()).
+		Str("family_id", rs.FamilyID.String()).
+		Str("session_id", rs.ID.String()).
+		Msg("revoked refresh token presented, revoking token family")
+
+	_ = s.refresh.RevokeFamily(ctx, rs.FamilyID)
+	s.sessions.forget(rs.FamilyID)
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID: &rs.UserID,
+		Type:   domain.EventRefreshReuse,
+		Details: map[string]any{
+			"family_id":  rs.FamilyID,
+			"session_id": rs.ID,
+		},
+	})
+}
+
+// Logout завершает сессию refresh-токена и отзывает access-токен, если он передан.
+func (s *Service) Logout(ctx context.Context, refreshToken, accessToken string) error {
+	if accessToken != "" {
+		if _, err := s.revokeAccess(ctx, accessToken); err != nil {
+			return err
+		}
+	}
 	if refreshToken == "" {
 		return nil
 	}
@@ -100,32 +343,135 @@ func (s *Service) Logout(ctx context.Context, refreshToken string) error {
 	if err != nil {
 		return nil
 	}
-	return s.refresh.RevokeByID(ctx, rs.ID)
+	if err := s.refresh.RevokeByID(ctx, rs.ID); err != nil {
+		return err
+	}
+	s.sessions.forget(rs.FamilyID)
+	s.recordEvent(ctx, domain.AuthEvent{
+		UserID:  &rs.UserID,
+		Type:    domain.EventLogout,
+		Details: map[string]any{"family_id": rs.FamilyID},
+	})
+	return nil
 }
 
 func (s *Service) LogoutAll(ctx context.Context, userID uuid.UUID) error {
-	return s.refresh.RevokeAllForUser(ctx, userID)
+	if err := s.refresh.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.revokeAllAccess(ctx, userID); err != nil {
+		return err
+	}
+	s.recordEvent(ctx, domain.AuthEvent{UserID: &userID, Type: domain.EventLogoutAll})
+	return nil
+}
+
+// ChangePassword меняет пароль после проверки текущего. Все остальные устройства
+// разлогиниваются; сессия sessionID (sid access-токена) сохраняется, но все
+// access-токены отзываются — текущему устройству нужно обновить токен через refresh.
+func (s *Service) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword, newPassword string, sessionID uuid.UUID) error {
+	u, err := s.users.ByID(ctx, userID)
+	if err != nil {
+		return domain.ErrInvalidCreds
+	}
+	if !s.checkPassword(ctx, u, currentPassword) {
+		return domain.ErrInvalidCreds
+	}
+	if err := s.checkNewPassword(ctx, u.Email, &u, newPassword); err != nil {
+		return err
+	}
+	newHash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.users.UpdatePassword(ctx, u.ID, newHash, s.keepPasswordHistory()); err != nil {
+		return err
+	}
+	if err := s.revokeAllAccess(ctx, u.ID); err != nil {
+		return err
+	}
+	s.recordEvent(ctx, domain.AuthEvent{UserID: &u.ID, Type: domain.EventPasswordChange})
+	return s.refresh.RevokeOthersForUser(ctx, u.ID, sessionID)
+}
+
+// ListSessions возвращает устройства, на которых пользователь сейчас залогинен.
+func (s *Service) ListSessions(ctx context.Context, userID uuid.UUID) ([]domain.DeviceSession, error) {
+	return s.refresh.ListActiveByUser(ctx, userID)
+}
+
+// RevokeSession разлогинивает одно устройство пользователя.
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	ok, err := s.refresh.RevokeFamilyForUser(ctx, sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return domain.ErrNotFound
+	}
+	s.sessions.forget(sessionID)
+	return nil
 }
 
+// StartPasswordResetOTP создаёт код сброса и отправляет его на почту.
+// Код возвращается вызывающему только в DevMode, иначе — пустая строка.
+// Повторный запрос раньше ResetOTPCooldown молча игнорируется, чтобы по ответу
+// нельзя было понять, существует ли аккаунт.
 func (s *Service) StartPasswordResetOTP(ctx context.Context, email string) (string, error) {
 	email = normEmail(email)
 	u, err := s.users.ByEmail(ctx, email)
 	if err != nil {
 		return "", nil
 	}
-	code, err := randomString(6)
+	if s.cfg.EmailVerification.RequireForPasswordReset && u.EmailVerifiedAt == nil {
+		log.Info().Str("user_id", u.ID.String()).Msg("password reset requested for unverified email, skipping")
+		return "", nil
+	}
+	if s.cfg.ResetOTPCooldown > 0 {
+		last, err := s.resets.LatestByUser(ctx, u.ID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return "", err
+		}
+		if err == nil && time.Since(last.CreatedAt) < s.cfg.ResetOTPCooldown {
+			log.Info().Str("user_id", u.ID.String()).Msg("password reset requested during cooldown, skipping")
+			return "", nil
+		}
+	}
+
+	code, err := s.sendResetCode(ctx, u)
 	if err != nil {
 		return "", err
 	}
-	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	s.recordEvent(ctx, domain.AuthEvent{UserID: &u.ID, Type: domain.EventPasswordResetStart})
+	return code, nil
+}
+
+// sendResetCode выпускает новый код сброса (старые аннулируются) и отправляет его на почту.
+func (s *Service) sendResetCode(ctx context.Context, u domain.User) (string, error) {
+	code, err := randomDigits(s.resetOTPLength())
 	if err != nil {
 		return "", err
 	}
-	_, err = s.resets.CreateOTP(ctx, u.ID, string(hash), time.Now().Add(s.cfg.ResetOTPTTL))
+	hash, err := s.hasher.Hash(code)
 	if err != nil {
 		return "", err
 	}
-	return code, nil
+	_, err = s.resets.CreateOTP(ctx, u.ID, hash, time.Now().Add(s.cfg.ResetOTPTTL))
+	if err != nil {
+		return "", err
+	}
+
+	err = s.sendMail(ctx, tmplPasswordReset, u.Email, map[string]any{
+		"Email": u.Email,
+		"Code":  code,
+		"TTL":   humanTTL(s.cfg.ResetOTPTTL),
+	})
+	if err != nil {
+		return "", fmt.Errorf("send reset code: %w", err)
+	}
+	if s.cfg.DevMode {
+		return code, nil
+	}
+	return "", nil
 }
 
 func (s *Service) ConfirmPasswordResetOTP(ctx context.Context, email, code, newPassword string) error {
@@ -138,106 +484,394 @@ func (s *Service) ConfirmPasswordResetOTP(ctx context.Context, email, code, newP
 	if err != nil {
 		return domain.ErrInvalidOTP
 	}
-	if bcrypt.CompareHashAndPassword([]byte(pr.OTPHash), []byte(code)) != nil {
+	// слабый пароль отклоняем до проверки кода, чтобы не тратить попытку
+	if err := s.checkNewPassword(ctx, u.Email, &u, newPassword); err != nil {
+		return err
+	}
+	// попытку засчитываем до проверки: параллельные запросы не обойдут лимит
+	pr, err = s.resets.ClaimAttempt(ctx, pr.ID, s.resetOTPMaxAttempts())
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.ErrInvalidOTP
+	}
+	if err != nil {
+		return err
+	}
+	if ok, _, _ := s.hasher.Verify(pr.OTPHash, strings.TrimSpace(code)); !ok {
+		if pr.Attempts >= s.resetOTPMaxAttempts() {
+			log.Warn().Str("user_id", u.ID.String()).Int("attempts", pr.Attempts).Msg("password reset code invalidated after too many attempts")
+		}
+		s.recordEvent(ctx, domain.AuthEvent{
+			UserID:  &u.ID,
+			Type:    domain.EventPasswordResetFail,
+			Details: map[string]any{"attempts": pr.Attempts},
+		})
 		return domain.ErrInvalidOTP
 	}
-	if err := validatePassword(newPassword); err != nil {
+	newHash, err := s.hasher.Hash(newPassword)
+	if err != nil {
 		return err
 	}
-	newHash, _ := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
-	if err := s.users.UpdatePassword(ctx, u.ID, string(newHash)); err != nil {
+	// код гасим до смены пароля: из двух запросов с верным кодом пройдёт один
+	used, err := s.resets.MarkUsed(ctx, pr.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return domain.ErrInvalidOTP
+	}
+	if err := s.users.UpdatePassword(ctx, u.ID, newHash, s.keepPasswordHistory()); err != nil {
 		return err
 	}
-	_ = s.resets.MarkUsed(ctx, pr.ID)
 	_ = s.refresh.RevokeAllForUser(ctx, u.ID)
+	_ = s.revokeAllAccess(ctx, u.ID)
+	s.recordEvent(ctx, domain.AuthEvent{UserID: &u.ID, Type: domain.EventPasswordResetDone})
 	return nil
 }
 
-func (s *Service) ValidateAccess(ctx context.Context, access string) (uuid.UUID, error) {
-	claims, err := s.parseAccess(access)
+func (s *Service) resetOTPLength() int {
+	if s.cfg.ResetOTPLength <= 0 {
+		return 6
+	}
+	return s.cfg.ResetOTPLength
+}
+
+func (s *Service) resetOTPMaxAttempts() int {
+	if s.cfg.ResetOTPMaxAttempts <= 0 {
+		return 5
+	}
+	return s.cfg.ResetOTPMaxAttempts
+}
+
+// AccessInfo — то, что известно о владельце валидного access-токена.
+type AccessInfo struct {
+	UserID uuid.UUID
+	// SessionID — семья refresh-сессий (устройство), с которой выпущен токен.
+	SessionID     uuid.UUID
+	EmailVerified bool
+	Roles         []string
+	// ClientID — стороннее приложение, которому пользователь выдал токен; права
+	// такого токена ограничены Scopes, ролей у него нет.
+	ClientID  string
+	Scopes    []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+func (s *Service) ValidateAccess(ctx context.Context, access string) (AccessInfo, error) {
+	claims, err := s.parseToken(access, typAccess)
 	if err != nil {
-		return uuid.Nil, err
+		return AccessInfo{}, err
 	}
 
 	subStr, _ := claims["sub"].(string)
 	id, err := uuid.Parse(subStr)
 	if err != nil {
-		return uuid.Nil, err
+		return AccessInfo{}, err
+	}
+
+	sidStr, _ := claims["sid"].(string)
+	sid, err := uuid.Parse(sidStr)
+	if err != nil {
+		return AccessInfo{}, domain.ErrInvalidCreds
 	}
 
 	u, err := s.users.ByID(ctx, id)
 	if err != nil {
-		return uuid.Nil, domain.ErrInvalidCreds
+		return AccessInfo{}, domain.ErrInvalidCreds
+	}
+	if u.Blocked(time.Now()) {
+		return AccessInfo{}, domain.ErrInvalidCreds
+	}
+	revoked, err := s.accessRevoked(ctx, claims, u.TokensValidAfter)
+	if err != nil {
+		return AccessInfo{}, err
+	}
+	if revoked {
+		return AccessInfo{}, domain.ErrInvalidCreds
+	}
+	active, err := s.sessionActive(ctx, sid, u.ID)
+	if err != nil {
+		return AccessInfo{}, err
+	}
+	if !active {
+		return AccessInfo{}, domain.ErrInvalidCreds
+	}
+
+	info := AccessInfo{UserID: u.ID, SessionID: sid, EmailVerified: u.EmailVerifiedAt != nil}
+	if clientID, _ := claims["client_id"].(string); clientID != "" {
+		scope, _ := claims["scope"].(string)
+		info.ClientID, info.Scopes, info.Roles = clientID, nonNilScopes(strings.Fields(scope)), []string{}
+	} else {
+		// роли берём из базы, а не из claim'а: отзыв роли действует сразу для тех, кто проверяет токен через /validate
+		if info.Roles, err = s.roles.ListByUser(ctx, u.ID); err != nil {
+			return AccessInfo{}, err
+		}
+	}
+	if iat, _ := claims.GetIssuedAt(); iat != nil {
+		info.IssuedAt = iat.Time
 	}
-	if u.IsBlocked {
-		return uuid.Nil, domain.ErrInvalidCreds
+	if exp, _ := claims.GetExpirationTime(); exp != nil {
+		info.ExpiresAt = exp.Time
 	}
+	return info, nil
+}
 
-	return u.ID, nil
+// sessionActive проверяет, что устройство, с которого выпущен токен, не разлогинено.
+func (s *Service) sessionActive(ctx context.Context, sid, userID uuid.UUID) (bool, error) {
+	now := time.Now()
+	if active, ok := s.sessions.get(sid, now); ok {
+		return active, nil
+	}
+	active, err := s.refresh.FamilyActive(ctx, sid, userID)
+	if err != nil {
+		return false, err
+	}
+	s.sessions.put(sid, active, now)
+	return active, nil
 }
 
-func (s *Service) issuePair(ctx context.Context, userID uuid.UUID) (TokenPair, error) {
+// issuePair выпускает новую пару токенов. Если parent задан, новая refresh-сессия
+// продолжает его семью (вместе с приложением и правами), иначе начинается новая (новый логин).
+func (s *Service) issuePair(ctx context.Context, u domain.User, parent *domain.RefreshSession, client ClientInfo) (TokenPair, error) {
+	rs := domain.RefreshSession{
+		UserID:     u.ID,
+		FamilyID:   uuid.New(),
+		UserAgent:  optString(client.UserAgent),
+		IP:         optString(client.IP),
+		DeviceName: optString(client.DeviceName),
+	}
+	if parent != nil {
+		rs.FamilyID = parent.FamilyID
+		rs.ParentID = &parent.ID
+		rs.ClientID = parent.ClientID
+		rs.Scopes = parent.Scopes
+		if rs.DeviceName == nil {
+			rs.DeviceName = parent.DeviceName
+		}
+	}
+	return s.startSession(ctx, u, rs)
+}
+
+// startSession сохраняет refresh-сессию rs с новым токеном и подписывает access-токен для неё.
+func (s *Service) startSession(ctx context.Context, u domain.User, rs domain.RefreshSession) (TokenPair, error) {
 	rawRefresh, err := randomString(32)
 	if err != nil {
 		return TokenPair{}, err
 	}
-	_, err = s.refresh.Create(ctx, userID, sha256sum(rawRefresh), nil, nil, time.Now().Add(s.cfg.RefreshTTL))
-	if err != nil {
+	rs.TokenHash = sha256sum(rawRefresh)
+	rs.ExpiresAt = time.Now().Add(s.cfg.RefreshTTL)
+	if _, err := s.refresh.Create(ctx, rs); err != nil {
 		return TokenPair{}, err
 	}
-	access, err := s.signAccess(userID)
+
+	var access string
+	if rs.ClientID != nil {
+		access, err = s.signAppAccess(u, *rs.ClientID, rs.Scopes, rs.FamilyID)
+	} else {
+		var roles []string
+		if roles, err = s.roles.ListByUser(ctx, u.ID); err != nil {
+			return TokenPair{}, err
+		}
+		access, err = s.signAccess(u, roles, rs.FamilyID)
+	}
 	if err != nil {
 		return TokenPair{}, err
 	}
 	return TokenPair{AccessToken: access, RefreshToken: rawRefresh}, nil
 }
 
-func (s *Service) signAccess(userID uuid.UUID) (string, error) {
+// signAccess подписывает access-токен; sid привязывает его к семье refresh-сессий,
+// и после отзыва этой сессии токен перестаёт приниматься.
+func (s *Service) signAccess(u domain.User, roles []string, sid uuid.UUID) (string, error) {
+	if roles == nil {
+		roles = []string{}
+	}
+	return s.signToken(typAccess, s.cfg.AccessTTL, jwt.MapClaims{
+		"sub":            u.ID.String(),
+		"email_verified": u.EmailVerifiedAt != nil,
+		"roles":          roles,
+		"sid":            sid.String(),
+	})
+}
+
+// signAppAccess подписывает access-токен стороннего приложения: без ролей,
+// с client_id и правами, на которые согласился пользователь.
+func (s *Service) signAppAccess(u domain.User, clientID string, scopes []string, sid uuid.UUID) (string, error) {
+	return s.signToken(typAccess, s.cfg.AccessTTL, jwt.MapClaims{
+		"sub":            u.ID.String(),
+		"email_verified": u.EmailVerifiedAt != nil,
+		"roles":          []string{},
+		"sid":            sid.String(),
+		"client_id":      clientID,
+		"scope":          strings.Join(scopes, " "),
+	})
+}
+
+// signToken подписывает JWT текущим ключом; typ отличает access-токены от служебных.
+func (s *Service) signToken(typ string, ttl time.Duration, claims jwt.MapClaims) (string, error) {
 	now := time.Now()
-	claims := jwt.MapClaims{
-		"sub": userID.String(),
-		"typ": "access",
-		"iss": s.cfg.Issuer,
-		"iat": now.Unix(),
-		"exp": now.Add(s.cfg.AccessTTL).Unix(),
+	claims["typ"] = typ
+	claims["iss"] = s.cfg.Issuer
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+	claims["jti"] = uuid.NewString()
+
+	key, err := s.ring.Signing(now)
+	if err != nil {
+		return "", err
 	}
-	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.cfg.JWTSecret))
+	t := jwt.NewWithClaims(key.Method, claims)
+	t.Header["kid"] = key.ID
+	return t.SignedString(key.Private)
 }
 
-func (s *Service) parseAccess(token string) (jwt.MapClaims, error) {
+func (s *Service) parseToken(token, typ string) (jwt.MapClaims, error) {
 	t, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
-		if t.Method != jwt.SigningMethodHS256 {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := s.ring.Lookup(kid, time.Now())
+		if !ok {
+			return nil, errors.New("unknown kid")
+		}
+		if t.Method.Alg() != key.Method.Alg() {
 			return nil, errors.New("bad alg")
 		}
-		return []byte(s.cfg.JWTSecret), nil
-	})
+		return key.Public(), nil
+	},
+		jwt.WithValidMethods([]string{keys.AlgRS256, keys.AlgEdDSA}),
+		jwt.WithIssuer(s.cfg.Issuer),
+		jwt.WithExpirationRequired(),
+	)
 	if err != nil || !t.Valid {
 		return nil, domain.ErrInvalidCreds
 	}
 	claims, ok := t.Claims.(jwt.MapClaims)
-	if !ok || claims["typ"] != "access" {
+	if !ok || claims["typ"] != typ {
 		return nil, domain.ErrInvalidCreds
 	}
 	return claims, nil
 }
 
+// JWKS возвращает публичные ключи для проверки access-токенов другими сервисами.
+func (s *Service) JWKS() []keys.JWK {
+	ks := s.ring.Verification(time.Now())
+	out := make([]keys.JWK, 0, len(ks))
+	for _, k := range ks {
+		out = append(out, k.JWK())
+	}
+	return out
+}
+
+// checkBlocked возвращает *domain.BlockedError, если блокировка пользователя действует сейчас.
+func checkBlocked(u domain.User) error {
+	if u.Blocked(time.Now()) {
+		return &domain.BlockedError{Until: u.BlockedUntil}
+	}
+	return nil
+}
+
+// recordEvent пишет событие в журнал; ошибка записи не должна ломать основной сценарий.
+func (s *Service) recordEvent(ctx context.Context, e domain.AuthEvent) {
+	if client := clientFrom(ctx); e.IP == nil && e.UserAgent == nil {
+		e.IP, e.UserAgent = optString(client.IP), optString(client.UserAgent)
+	}
+	if err := s.events.Record(ctx, e); err != nil {
+		log.Error().Err(err).Str("type", e.Type).Msg("failed to record auth event")
+	}
+}
+
 func normEmail(s string) string {
 	return strings.ToLower(strings.TrimSpace(s))
 }
 
-func validatePassword(pw string) error {
-	if len(pw) < 8 || len(pw) > 72 {
-		return errors.New("password length must be 8..72")
+func optString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// checkPassword сверяет пароль с хэшем пользователя. Устаревший хэш (bcrypt или
+// прежние параметры argon2id) после успешной проверки тихо пересчитывается.
+func (s *Service) checkPassword(ctx context.Context, u domain.User, pw string) bool {
+	ok, rehash, err := s.hasher.Verify(u.PasswordHash, pw)
+	if err != nil && u.PasswordHash != "" {
+		log.Error().Err(err).Str("user_id", u.ID.String()).Msg("failed to verify password hash")
+	}
+	if !ok {
+		return false
+	}
+	if rehash {
+		if h, err := s.hasher.Hash(pw); err == nil {
+			if err := s.users.RehashPassword(ctx, u.ID, u.PasswordHash, h); err != nil {
+				log.Warn().Err(err).Str("user_id", u.ID.String()).Msg("failed to upgrade password hash")
+			}
+		}
+	}
+	return true
+}
+
+// checkNewPassword проверяет новый пароль по политике (*domain.PasswordPolicyError).
+// u — владелец при смене пароля (nil при регистрации); историю сверяем, только если
+// остальные проверки пройдены: каждый хэш — это полноценный argon2id.
+func (s *Service) checkNewPassword(ctx context.Context, email string, u *domain.User, pw string) error {
+	violations := s.policy.Check(pw, email)
+	if len(violations) == 0 && u != nil && s.policy.History() > 0 {
+		reused, err := s.passwordReused(ctx, *u, pw)
+		if err != nil {
+			return err
+		}
+		if reused {
+			violations = append(violations, password.Reused)
+		}
+	}
+	if len(violations) > 0 {
+		return &domain.PasswordPolicyError{Violations: violations}
 	}
 	return nil
 }
 
+// passwordReused сравнивает пароль с текущим и прежними из истории.
+func (s *Service) passwordReused(ctx context.Context, u domain.User, pw string) (bool, error) {
+	hashes := []string{u.PasswordHash}
+	if keep := s.keepPasswordHistory(); keep > 0 {
+		prev, err := s.users.PasswordHistory(ctx, u.ID, keep)
+		if err != nil {
+			return false, err
+		}
+		hashes = append(hashes, prev...)
+	}
+	for _, h := range hashes {
+		if ok, _, _ := s.hasher.Verify(h, pw); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// keepPasswordHistory — сколько прежних хэшей хранить: текущий пароль лежит в users.
+func (s *Service) keepPasswordHistory() int {
+	return max(s.policy.History()-1, 0)
+}
+
 func randomString(n int) (string, error) {
 	b := make([]byte, n)
 	_, err := rand.Read(b)
 	return base64.RawURLEncoding.EncodeToString(b), err
 }
 
+// randomDigits возвращает n равномерно распределённых случайных цифр.
+func randomDigits(n int) (string, error) {
+	b := make([]byte, n)
+	for i := range b {
+		d, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		b[i] = byte('0' + d.Int64())
+	}
+	return string(b), nil
+}
+
 func sha256sum(s string) []byte {
 	h := sha256.Sum256([]byte(s))
 	return h[:]
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxCachedSessions ограничивает память кэша; при переполнении выбрасываются истёкшие записи,
+// а если их нет — весь кэш.
+const maxCachedSessions = 100_000
+
+type cachedSession struct {
+	active bool
+	until  time.Time
+}
+
+// sessionCache помнит результат проверки sid access-токена на ttl, чтобы не ходить
+// в refresh_sessions на каждый запрос. Отзыв сессии на другой реплике становится
+// виден не позже чем через ttl.
+type sessionCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[uuid.UUID]cachedSession
+}
+
+func newSessionCache(ttl time.Duration) *sessionCache {
+	return &sessionCache{ttl: ttl, entries: make(map[uuid.UUID]cachedSession)}
+}
+
+func (c *sessionCache) get(sid uuid.UUID, now time.Time) (active, ok bool) {
+	if c.ttl <= 0 {
+		return false, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[sid]
+	if !ok || !now.Before(e.until) {
+		return false, false
+	}
+	return e.active, true
+}
+
+func (c *sessionCache) put(sid uuid.UUID, active bool, now time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCachedSessions {
+		for id, e := range c.entries {
+			if !now.Before(e.until) {
+				delete(c.entries, id)
+			}
+		}
+		if len(c.entries) >= maxCachedSessions {
+			c.entries = make(map[uuid.UUID]cachedSession)
+		}
+	}
+	c.entries[sid] = cachedSession{active: active, until: now.Add(c.ttl)}
+}
+
+// forget сбрасывает запись после отзыва сессии на этой реплике.
+func (c *sessionCache) forget(sid uuid.UUID) {
+	c.mu.Lock()
+	delete(c.entries, sid)
+	c.mu.Unlock()
+}
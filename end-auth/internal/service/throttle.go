@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"auth/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// LoginThrottleConfig — защита входа от перебора. Счётчики ведутся отдельно по аккаунту
+// и по IP и хранятся в БД, поэтому общие для всех реплик.
+type LoginThrottleConfig struct {
+	// ResetAfter — сколько должно пройти без неудач, чтобы счётчик начался заново.
+	ResetAfter time.Duration
+	Account    ThrottleRule
+	IP         ThrottleRule
+}
+
+// ThrottleRule: первые FreeAttempts неудач проходят без задержки, дальше задержка
+// удваивается от BaseDelay до MaxDelay; после LockoutAfter неудач вход закрыт на LockoutFor.
+// Нулевые значения отключают соответствующую часть правила.
+type ThrottleRule struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	LockoutAfter int
+	LockoutFor   time.Duration
+}
+
+// lockFor — на сколько закрыть вход после failures-й неудачи подряд.
+func (r ThrottleRule) lockFor(failures int) time.Duration {
+	if r.LockoutAfter > 0 && failures >= r.LockoutAfter {
+		return r.LockoutFor
+	}
+	if r.BaseDelay <= 0 || failures <= r.FreeAttempts {
+		return 0
+	}
+	d := r.BaseDelay
+	for i := r.FreeAttempts + 1; i < failures; i++ {
+		d *= 2
+		if r.MaxDelay > 0 && d >= r.MaxDelay {
+			break
+		}
+	}
+	if r.MaxDelay > 0 && d > r.MaxDelay {
+		d = r.MaxDelay
+	}
+	return d
+}
+
+type throttleKey struct {
+	key  string
+	rule ThrottleRule
+}
+
+func (s *Service) loginKeys(email, ip string) []throttleKey {
+	keys := []throttleKey{{key: "account:" + email, rule: s.cfg.LoginThrottle.Account}}
+	if ip != "" {
+		keys = append(keys, throttleKey{key: "ip:" + ip, rule: s.cfg.LoginThrottle.IP})
+	}
+	return keys
+}
+
+// checkThrottle возвращает *domain.LockedError, если хотя бы по одному ключу вход ещё закрыт.
+func (s *Service) checkThrottle(ctx context.Context, keys []throttleKey) error {
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		names = append(names, k.key)
+	}
+	rows, err := s.throttle.Get(ctx, names)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, lf := range rows {
+		if lf.LockedUntil != nil && lf.LockedUntil.After(now) {
+			wait = max(wait, lf.LockedUntil.Sub(now))
+		}
+	}
+	if wait > 0 {
+		return &domain.LockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordFailure увеличивает счётчики и при необходимости закрывает вход.
+// Ошибки только логируются: ответ пользователю уже определён неудачной попыткой.
+func (s *Service) recordFailure(ctx context.Context, keys []throttleKey, userID *uuid.UUID) {
+	now := time.Now()
+	resetAfter := s.cfg.LoginThrottle.ResetAfter
+	if resetAfter <= 0 {
+		resetAfter = time.Hour
+	}
+
+	_, _ = s.throttle.DeleteStale(ctx, now.Add(-resetAfter))
+	for _, k := range keys {
+		n, err := s.throttle.RecordFailure(ctx, k.key, now, now.Add(-resetAfter))
+		if err != nil {
+			continue
+		}
+		d := k.rule.lockFor(n)
+		if d <= 0 {
+			continue
+		}
+		if err := s.throttle.SetLockedUntil(ctx, k.key, now.Add(d)); err != nil {
+			continue
+		}
+		if k.rule.LockoutAfter > 0 && n == k.rule.LockoutAfter {
+			log.Warn().Str("key", k.key).Int("failures", n).Dur("locked_for", d).Msg("login locked out after repeated failures")
+			s.recordEvent(ctx, domain.AuthEvent{
+				UserID: userID,
+				Type:   domain.EventLoginLockout,
+				Details: map[string]any{
+					"key":        k.key,
+					"failures":   n,
+					"locked_for": d.String(),
+				},
+			})
+		}
+	}
+}
+
+func (s *Service) clearFailures(ctx context.Context, key string) {
+	_ = s.throttle.Clear(ctx, key)
+}
@@ -80,7 +80,7 @@ func main() {
 	tsvc := svc.NewTrainingService(trepo)
 	esvc := svc.NewExerciseService(erepo)
 
-	srv := app.SetupServer(tsvc, esvc, cfg.Http.Addr, cfg.Auth.BaseURL)
+	srv := app.SetupServer(tsvc, esvc, cfg.Http.Addr, cfg.Auth)
 
 	if err := srv.StartServer(); err != nil {
 		log.Fatal().Err(err).
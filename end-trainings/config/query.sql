@@ -57,6 +57,35 @@ INNER JOIN exercise_to_tag et ON e.id = et.exercise_id
 WHERE et.tag_id = $1
 ORDER BY e.id;
 
+-- name: CreateExercise :one
+INSERT INTO exercise (title, description, video_url, image_url)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- name: UpdateExercise :one
+UPDATE exercise
+SET title = $2, description = $3, video_url = $4, image_url = $5
+WHERE id = $1
+RETURNING id;
+
+-- name: DeleteExercise :execrows
+DELETE FROM exercise WHERE id = $1;
+
+-- name: ExerciseInUse :one
+-- Упражнение уже есть в тренировках пользователей или в глобальных тренировках
+SELECT CAST(
+    EXISTS (SELECT 1 FROM trained_exercise te WHERE te.exercise_id = sqlc.arg(exercise_id))
+    OR EXISTS (SELECT 1 FROM global_training_exercise gte WHERE gte.exercise_id = sqlc.arg(exercise_id))
+    AS boolean) AS in_use;
+
+-- name: DeleteExerciseTags :exec
+DELETE FROM exercise_to_tag WHERE exercise_id = $1;
+
+-- name: AddExerciseTag :exec
+INSERT INTO exercise_to_tag (exercise_id, tag_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
 -- name: GetTrainingsByUser :many
 SELECT 
     t.id,
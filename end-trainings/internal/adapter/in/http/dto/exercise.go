@@ -31,3 +31,12 @@ type GetExercisesByMultipleTagsRequest struct {
 type GetPopularTagsRequest struct {
 	Limit int `json:"limit" form:"limit" binding:"min=1,max=50" example:"10" description:"Лимит тегов (1-50)"`
 }
+
+// ExerciseRequest представляет запрос на создание или изменение упражнения каталога
+type ExerciseRequest struct {
+	Title       string  `json:"title" binding:"required" example:"Жим лёжа" description:"Название упражнения"`
+	Description string  `json:"description" example:"Базовое упражнение для развития грудных мышц" description:"Описание упражнения"`
+	VideoURL    string  `json:"video_url" example:"https://example.com/video.mp4" description:"Ссылка на видео с техникой выполнения"`
+	ImageURL    string  `json:"image_url" example:"https://example.com/image.png" description:"Ссылка на картинку"`
+	TagIDs      []int64 `json:"tag_ids" example:"1,2" description:"ID тегов; заменяют прежние теги упражнения"`
+}
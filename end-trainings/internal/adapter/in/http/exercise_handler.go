@@ -1,12 +1,14 @@
 package httpin
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/EnduranNSU/trainings/internal/adapter/in/http/dto"
 	svcexercise "github.com/EnduranNSU/trainings/internal/domain"
+	"github.com/EnduranNSU/trainings/internal/service"
 )
 
 type ExerciseHandler struct {
@@ -264,6 +266,122 @@ func (h *ExerciseHandler) GetPopularTags(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// CreateExercise добавляет упражнение в каталог
+// @Summary      Создать упражнение
+// @Description  Добавляет упражнение в каталог. Доступно ролям admin и content_editor
+// @Tags         exercises
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.ExerciseRequest true "Данные упражнения"
+// @Success      201  {object}  dto.ExerciseResponse
+// @Failure      400  {object}  dto.ErrorResponse
+// @Failure      403  {object}  dto.ErrorResponse
+// @Failure      500  {object}  dto.ErrorResponse
+// @Router       /exercises [post]
+func (h *ExerciseHandler) CreateExercise(c *gin.Context) {
+	var req dto.ExerciseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad json"})
+		return
+	}
+
+	exercise, err := h.svc.CreateExercise(c.Request.Context(), exerciseCmd(0, req))
+	if err != nil {
+		abortExerciseError(c, err, "failed to create exercise")
+		return
+	}
+
+	c.JSON(http.StatusCreated, h.exerciseToResponse(exercise))
+}
+
+// UpdateExercise изменяет упражнение каталога
+// @Summary      Изменить упражнение
+// @Description  Заменяет данные и теги упражнения. Доступно ролям admin и content_editor
+// @Tags         exercises
+// @Accept       json
+// @Produce      json
+// @Param        id path int64 true "Exercise ID"
+// @Param        request body dto.ExerciseRequest true "Данные упражнения"
+// @Success      200  {object}  dto.ExerciseResponse
+// @Failure      400  {object}  dto.ErrorResponse
+// @Failure      403  {object}  dto.ErrorResponse
+// @Failure      404  {object}  dto.ErrorResponse
+// @Failure      500  {object}  dto.ErrorResponse
+// @Router       /exercises/{id} [put]
+func (h *ExerciseHandler) UpdateExercise(c *gin.Context) {
+	exerciseID, err := parseInt64Param(c, "id")
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid exercise id"})
+		return
+	}
+
+	var req dto.ExerciseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "bad json"})
+		return
+	}
+
+	exercise, err := h.svc.UpdateExercise(c.Request.Context(), exerciseCmd(exerciseID, req))
+	if err != nil {
+		abortExerciseError(c, err, "failed to update exercise")
+		return
+	}
+
+	c.JSON(http.StatusOK, h.exerciseToResponse(exercise))
+}
+
+// DeleteExercise удаляет упражнение из каталога
+// @Summary      Удалить упражнение
+// @Description  Удаляет упражнение, если оно не используется в тренировках. Доступно ролям admin и content_editor
+// @Tags         exercises
+// @Produce      json
+// @Param        id path int64 true "Exercise ID"
+// @Success      204
+// @Failure      400  {object}  dto.ErrorResponse
+// @Failure      403  {object}  dto.ErrorResponse
+// @Failure      404  {object}  dto.ErrorResponse
+// @Failure      409  {object}  dto.ErrorResponse "Упражнение используется в тренировках"
+// @Failure      500  {object}  dto.ErrorResponse
+// @Router       /exercises/{id} [delete]
+func (h *ExerciseHandler) DeleteExercise(c *gin.Context) {
+	exerciseID, err := parseInt64Param(c, "id")
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid exercise id"})
+		return
+	}
+
+	if err := h.svc.DeleteExercise(c.Request.Context(), exerciseID); err != nil {
+		abortExerciseError(c, err, "failed to delete exercise")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func exerciseCmd(id int64, req dto.ExerciseRequest) svcexercise.ExerciseCmd {
+	return svcexercise.ExerciseCmd{
+		ID:          id,
+		Title:       req.Title,
+		Description: req.Description,
+		VideoUrl:    req.VideoURL,
+		ImageUrl:    req.ImageURL,
+		TagIDs:      req.TagIDs,
+	}
+}
+
+func abortExerciseError(c *gin.Context, err error, internal string) {
+	switch {
+	case errors.Is(err, service.ErrEmptyTitle), errors.Is(err, service.ErrInvalidTagID), errors.Is(err, service.ErrTagNotFound):
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrExerciseNotFound):
+		c.AbortWithStatusJSON(http.StatusNotFound, dto.ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrExerciseInUse):
+		c.AbortWithStatusJSON(http.StatusConflict, dto.ErrorResponse{Error: err.Error()})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrorResponse{Error: internal})
+	}
+}
+
 func (h *ExerciseHandler) exerciseToResponse(exercise *svcexercise.Exercise) dto.ExerciseResponse {
 	var tags []dto.TagResponse
 	if exercise.Tags != nil {
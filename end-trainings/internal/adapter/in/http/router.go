@@ -5,6 +5,7 @@
 package httpin
 
 import (
+	"github.com/EnduranNSU/authkit"
 	"github.com/gin-gonic/gin"
 
 	_ "github.com/EnduranNSU/trainings/docs"
@@ -17,7 +18,7 @@ import (
 // @version 1.0
 // @description Сервис информации о тренировках и упражнения
 // @BasePath /api/v1
-func NewGinRouter(training *TrainingHandler, exercise *ExerciseHandler, authBaseURL string) *gin.Engine {
+func NewGinRouter(training *TrainingHandler, exercise *ExerciseHandler, auth AuthMiddlewareConfig) *gin.Engine {
 	r := gin.New()
 	r.Use(gin.Logger(), gin.Recovery())
 
@@ -26,8 +27,9 @@ func NewGinRouter(training *TrainingHandler, exercise *ExerciseHandler, authBase
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	api := r.Group("/api/v1")
-	authMW := NewAuthMiddleware(authBaseURL)
-	api.Use(authMW.Handle)
+	authMW := NewAuthMiddleware(auth)
+	// поиск по тегам — POST только из-за тела запроса, данные он не меняет
+	api.Use(authMW.Handle, RequireScopeByMethod(ScopeTrainingsRead, ScopeTrainingsWrite, "POST /api/v1/exercises/by-tags"))
 	{
 		// Training routes
 		trainings := api.Group("/trainings")
@@ -89,6 +91,12 @@ func NewGinRouter(training *TrainingHandler, exercise *ExerciseHandler, authBase
 			exercises.POST("/by-tags", exercise.GetExercisesByMultipleTags)
 			exercises.GET("/:id/tags", exercise.GetExerciseTags)
 			exercises.GET("/:id", exercise.GetExerciseByID)
+
+			// Каталог редактируют только администраторы и редакторы контента
+			catalog := exercises.Group("", RequireRoles(authkit.RoleAdmin, authkit.RoleContentEditor))
+			catalog.POST("", exercise.CreateExercise)
+			catalog.PUT("/:id", exercise.UpdateExercise)
+			catalog.DELETE("/:id", exercise.DeleteExercise)
 		}
 
 		// Tag routes
@@ -98,5 +106,16 @@ func NewGinRouter(training *TrainingHandler, exercise *ExerciseHandler, authBase
 		}
 	}
 
+	// Внутренние маршруты для других сервисов (токены client_credentials)
+	internal := r.Group("/internal/v1", authMW.RequireService(ScopeTrainingsRead))
+	{
+		users := internal.Group("/users/:user_id", userFromPath)
+		{
+			users.GET("/trainings", training.GetTrainingsByUser)
+			users.GET("/trainings/current", training.GetCurrentTraining)
+			users.GET("/trainings/today", training.GetTodaysTraining)
+		}
+	}
+
 	return r
 }
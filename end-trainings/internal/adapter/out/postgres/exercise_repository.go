@@ -142,6 +142,101 @@ func (r *ExerciseRepositoryImpl) SearchExercises(ctx context.Context, filter dom
 	return exercises, nil
 }
 
+func (r *ExerciseRepositoryImpl) CreateExercise(ctx context.Context, cmd domain.ExerciseCmd) (*domain.Exercise, error) {
+	return r.saveExercise(ctx, "CreateExercise", cmd, func(q *gen.Queries) (int64, error) {
+		return q.CreateExercise(ctx, gen.CreateExerciseParams{
+			Title:       cmd.Title,
+			Description: cmd.Description,
+			VideoUrl:    cmd.VideoUrl,
+			ImageUrl:    cmd.ImageUrl,
+		})
+	})
+}
+
+func (r *ExerciseRepositoryImpl) UpdateExercise(ctx context.Context, cmd domain.ExerciseCmd) (*domain.Exercise, error) {
+	return r.saveExercise(ctx, "UpdateExercise", cmd, func(q *gen.Queries) (int64, error) {
+		return q.UpdateExercise(ctx, gen.UpdateExerciseParams{
+			ID:          cmd.ID,
+			Title:       cmd.Title,
+			Description: cmd.Description,
+			VideoUrl:    cmd.VideoUrl,
+			ImageUrl:    cmd.ImageUrl,
+		})
+	})
+}
+
+// saveExercise в одной транзакции сохраняет упражнение и заменяет его теги.
+func (r *ExerciseRepositoryImpl) saveExercise(ctx context.Context, op string, cmd domain.ExerciseCmd, save func(q *gen.Queries) (int64, error)) (*domain.Exercise, error) {
+	jsonData := logging.MarshalLogData(map[string]interface{}{
+		"exercise_id": cmd.ID,
+		"title":       cmd.Title,
+		"tag_ids":     cmd.TagIDs,
+	})
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		logging.Error(err, op, jsonData, "failed to begin transaction")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	q := r.q.WithTx(tx)
+
+	id, err := save(q)
+	if err == sql.ErrNoRows {
+		logging.Warn(op, jsonData, "exercise not found")
+		return nil, err
+	}
+	if err != nil {
+		logging.Error(err, op, jsonData, "failed to save exercise")
+		return nil, err
+	}
+
+	if err := q.DeleteExerciseTags(ctx, id); err != nil {
+		logging.Error(err, op, jsonData, "failed to clear exercise tags")
+		return nil, err
+	}
+	for _, tagID := range cmd.TagIDs {
+		if err := q.AddExerciseTag(ctx, gen.AddExerciseTagParams{ExerciseID: id, TagID: tagID}); err != nil {
+			logging.Error(err, op, jsonData, "failed to add exercise tag")
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logging.Error(err, op, jsonData, "failed to commit transaction")
+		return nil, err
+	}
+
+	logging.Debug(op, jsonData, "successfully saved exercise")
+	return r.GetExerciseByID(ctx, id)
+}
+
+func (r *ExerciseRepositoryImpl) DeleteExercise(ctx context.Context, id int64) (int64, error) {
+	rows, err := r.q.DeleteExercise(ctx, id)
+	jsonData := logging.MarshalLogData(map[string]interface{}{
+		"exercise_id": id,
+	})
+	if err != nil {
+		logging.Error(err, "DeleteExercise", jsonData, "failed to delete exercise")
+		return 0, err
+	}
+	logging.Debug("DeleteExercise", jsonData, "successfully deleted exercise")
+	return rows, nil
+}
+
+func (r *ExerciseRepositoryImpl) ExerciseInUse(ctx context.Context, id int64) (bool, error) {
+	inUse, err := r.q.ExerciseInUse(ctx, id)
+	if err != nil {
+		jsonData := logging.MarshalLogData(map[string]interface{}{
+			"exercise_id": id,
+		})
+		logging.Error(err, "ExerciseInUse", jsonData, "failed to check exercise usage")
+		return false, err
+	}
+	return inUse, nil
+}
+
 func (r *ExerciseRepositoryImpl) GetAllTags(ctx context.Context) ([]*domain.Tag, error) {
 	tags, err := r.q.GetAllTags(ctx)
 	if err != nil {
@@ -2,6 +2,7 @@ package app
 
 import (
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 
@@ -27,6 +28,10 @@ type Config struct {
 
 type AuthConfig struct {
 	BaseURL string `mapstructure:"base_url" default:"http://localhost:8081"`
+	// Mode: local — проверка подписи по JWKS, remote — вызов auth/api/v1/validate.
+	Mode    string        `mapstructure:"mode" default:"local" validate:"oneof=local remote"`
+	Issuer  string        `mapstructure:"issuer" default:"enduran-auth"`
+	JWKSTTL time.Duration `mapstructure:"jwks_ttl" default:"5m"`
 }
 
 type HttpConfig struct {
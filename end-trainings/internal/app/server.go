@@ -17,23 +17,28 @@ type Server struct {
 	TrainingSvc svc.TrainingService
 	ExerciseSvc svc.ExerciseService
 	Addr        string
-	AuthBaseURL string
+	Auth        AuthConfig
 }
 
 func SetupServer(trainingSvc svc.TrainingService,
-	exerciseSvc svc.ExerciseService, addr string, authBaseURL string) *Server {
+	exerciseSvc svc.ExerciseService, addr string, auth AuthConfig) *Server {
 	return &Server{
 		TrainingSvc: trainingSvc,
 		ExerciseSvc: exerciseSvc,
 		Addr:        addr,
-		AuthBaseURL: authBaseURL,
+		Auth:        auth,
 	}
 }
 
 func (s *Server) StartServer() error {
 	eh := httpin.NewExerciseHandler(s.ExerciseSvc)
 	th := httpin.NewTrainingHandler(s.TrainingSvc)
-	engine := httpin.NewGinRouter(th, eh, s.AuthBaseURL)
+	engine := httpin.NewGinRouter(th, eh, httpin.AuthMiddlewareConfig{
+		BaseURL: s.Auth.BaseURL,
+		Mode:    s.Auth.Mode,
+		Issuer:  s.Auth.Issuer,
+		JWKSTTL: s.Auth.JWKSTTL,
+	})
 
 	srv := &http.Server{
 		Addr:              s.Addr,
@@ -53,6 +53,12 @@ type ExerciseRepository interface {
 	GetExerciseByID(ctx context.Context, id int64) (*Exercise, error)
 	GetExercisesByTag(ctx context.Context, tagID int64) ([]*Exercise, error)
 	SearchExercises(ctx context.Context, filter ExerciseFilter) ([]*Exercise, error)
+	CreateExercise(ctx context.Context, cmd ExerciseCmd) (*Exercise, error)
+	// UpdateExercise возвращает sql.ErrNoRows, если упражнения нет
+	UpdateExercise(ctx context.Context, cmd ExerciseCmd) (*Exercise, error)
+	// DeleteExercise возвращает число удалённых строк
+	DeleteExercise(ctx context.Context, id int64) (int64, error)
+	ExerciseInUse(ctx context.Context, id int64) (bool, error)
 	
 	// Теги
 	GetAllTags(ctx context.Context) ([]*Tag, error)
@@ -97,6 +97,16 @@ type AssignGlobalTrainingCmd struct {
 	PlannedDate      time.Time // Дата, на которую назначается тренировка
 }
 
+// ExerciseCmd — данные упражнения каталога; TagIDs заменяют прежний набор тегов.
+type ExerciseCmd struct {
+	ID          int64 // при создании не используется
+	Title       string
+	Description string
+	VideoUrl    string
+	ImageUrl    string
+	TagIDs      []int64
+}
+
 type ExerciseService interface {
 	GetAllExercises(ctx context.Context) ([]*Exercise, error)
 	GetExerciseByID(ctx context.Context, id int64) (*Exercise, error)
@@ -107,4 +117,9 @@ type ExerciseService interface {
 	GetExerciseTags(ctx context.Context, exerciseID int64) ([]*Tag, error)
 	GetExercisesByMultipleTags(ctx context.Context, tagIDs []int64) ([]*Exercise, error)
 	GetPopularTags(ctx context.Context, limit int) ([]*Tag, error)
+
+	// Редактирование каталога
+	CreateExercise(ctx context.Context, cmd ExerciseCmd) (*Exercise, error)
+	UpdateExercise(ctx context.Context, cmd ExerciseCmd) (*Exercise, error)
+	DeleteExercise(ctx context.Context, id int64) error
 }
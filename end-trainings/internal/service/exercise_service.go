@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"strings"
 
@@ -14,6 +15,9 @@ var (
 	ErrExerciseNotFound  = errors.New("exercise not found")
 	ErrTagNotFound       = errors.New("tag not found")
 	ErrEmptySearchQuery  = errors.New("search query cannot be empty")
+	ErrEmptyTitle        = errors.New("exercise title cannot be empty")
+	// ErrExerciseInUse — упражнение есть в тренировках: удаление стёрло бы их историю
+	ErrExerciseInUse = errors.New("exercise is used in trainings")
 )
 
 func NewExerciseService(repo domain.ExerciseRepository) domain.ExerciseService {
@@ -214,4 +218,62 @@ func (s *exerciseService) exerciseHasAllTags(exercise *domain.Exercise, tagIDs [
 	}
 
 	return true
-}
\ No newline at end of file
+}
+
+func (s *exerciseService) CreateExercise(ctx context.Context, cmd domain.ExerciseCmd) (*domain.Exercise, error) {
+	if err := s.validateExercise(ctx, &cmd); err != nil {
+		return nil, err
+	}
+	return s.repo.CreateExercise(ctx, cmd)
+}
+
+func (s *exerciseService) UpdateExercise(ctx context.Context, cmd domain.ExerciseCmd) (*domain.Exercise, error) {
+	if cmd.ID <= 0 {
+		return nil, ErrInvalidExerciseID
+	}
+	if err := s.validateExercise(ctx, &cmd); err != nil {
+		return nil, err
+	}
+	exercise, err := s.repo.UpdateExercise(ctx, cmd)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrExerciseNotFound
+	}
+	return exercise, err
+}
+
+func (s *exerciseService) DeleteExercise(ctx context.Context, id int64) error {
+	if id <= 0 {
+		return ErrInvalidExerciseID
+	}
+	inUse, err := s.repo.ExerciseInUse(ctx, id)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrExerciseInUse
+	}
+	rows, err := s.repo.DeleteExercise(ctx, id)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrExerciseNotFound
+	}
+	return nil
+}
+
+func (s *exerciseService) validateExercise(ctx context.Context, cmd *domain.ExerciseCmd) error {
+	cmd.Title = strings.TrimSpace(cmd.Title)
+	if cmd.Title == "" {
+		return ErrEmptyTitle
+	}
+	for _, tagID := range cmd.TagIDs {
+		if tagID <= 0 {
+			return ErrInvalidTagID
+		}
+		if _, err := s.repo.GetTagByID(ctx, tagID); err != nil {
+			return ErrTagNotFound
+		}
+	}
+	return nil
+}
@@ -74,7 +74,7 @@ func main() {
 	repo := postgres.NewUserInfoRepository(db)
 	svc := svcuserinfo.New(repo)
 
-	srv := app.SetupServer(svc, cfg.Http.Addr, cfg.Auth.BaseURL)
+	srv := app.SetupServer(svc, cfg.Http.Addr, cfg.Auth)
 
 	if err := srv.StartServer(); err != nil {
 		log.Fatal().Err(err).Msg("http server stopped")
@@ -1,73 +1,165 @@
 package httpin
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/EnduranNSU/authkit"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"github.com/EnduranNSU/end-user-info/internal/adapter/in/http/dto"
 )
 
-// AuthMiddleware ходит в Auth-сервис и валидирует access-токен.
-type AuthMiddleware struct {
-	client   *http.Client
-	authBase string
+const (
+	// AuthModeLocal — подпись и claims проверяются на месте по JWKS из Auth-сервиса.
+	AuthModeLocal = authkit.ModeLocal
+	// AuthModeRemote — каждый запрос валидируется вызовом auth/api/v1/validate.
+	AuthModeRemote = authkit.ModeRemote
+)
+
+type AuthMiddlewareConfig struct {
+	BaseURL string
+	Mode    string
+	Issuer  string
+	JWKSTTL time.Duration
 }
 
-func NewAuthMiddleware(authBase string) *AuthMiddleware {
-	return &AuthMiddleware{
-		client: &http.Client{
-			Timeout: 2 * time.Second,
-		},
-		authBase: strings.TrimRight(authBase, "/"),
-	}
+// Права токенов сервисов и персональных токенов (выдаются в auth).
+const (
+	ScopeUserInfoRead  = "user-info:read"
+	ScopeUserInfoWrite = "user-info:write"
+)
+
+// AuthMiddleware валидирует access-токен и кладёт userID и роли в контекст.
+type AuthMiddleware struct {
+	verifier authkit.TokenVerifier
 }
 
-type validateResponse struct {
-	UserID string `json:"user_id"`
+func NewAuthMiddleware(cfg AuthMiddlewareConfig) *AuthMiddleware {
+	return &AuthMiddleware{verifier: authkit.NewVerifier(authkit.Config{
+		BaseURL: cfg.BaseURL,
+		Mode:    cfg.Mode,
+		Issuer:  cfg.Issuer,
+		JWKSTTL: cfg.JWKSTTL,
+	})}
 }
 
+// Handle пропускает только токены пользователей; токены сервисов получают 403.
 func (m *AuthMiddleware) Handle(c *gin.Context) {
-	h := c.GetHeader("Authorization")
-	if len(h) < 7 || !strings.EqualFold(h[:7], "bearer ") {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "no_bearer"})
+	p, ok := m.verify(c)
+	if !ok {
 		return
 	}
-
-	req, err := http.NewRequestWithContext(
-		c.Request.Context(),
-		http.MethodGet,
-		m.authBase+"/api/v1/validate",
-		nil,
-	)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "auth_unavailable"})
+	if p.IsService() {
+		c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{Error: "user_token_required"})
 		return
 	}
-	req.Header.Set("Authorization", h)
 
-	resp, err := m.client.Do(req)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "auth_unavailable"})
-		return
+	c.Set("userID", p.UserID)
+	c.Set("roles", p.Roles)
+	c.Set("scoped", p.Scoped)
+	c.Set("scopes", p.Scopes)
+
+	c.Next()
+}
+
+// RequireScopeByMethod ограничивает персональные токены: для чтения (GET, HEAD)
+// нужно право read, для остальных методов — write. Обычные токены пользователя
+// проходят без проверки. Ставится после AuthMiddleware.Handle.
+func RequireScopeByMethod(read, write string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if scoped, _ := c.Get("scoped"); scoped != true {
+			c.Next()
+			return
+		}
+		need := write
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			need = read
+		}
+		v, _ := c.Get("scopes")
+		have, _ := v.([]string)
+		if !slices.Contains(have, need) {
+			c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{Error: "insufficient_scope"})
+			return
+		}
+		c.Next()
 	}
-	defer resp.Body.Close()
+}
 
-	if resp.StatusCode != http.StatusOK {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "invalid_token"})
-		return
+// RequireService защищает внутренние маршруты: пропускает только токены сервисов,
+// у которых есть все перечисленные права.
+func (m *AuthMiddleware) RequireService(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := m.verify(c)
+		if !ok {
+			return
+		}
+		if !p.IsService() {
+			c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{Error: "service_token_required"})
+			return
+		}
+		for _, s := range scopes {
+			if !slices.Contains(p.Scopes, s) {
+				c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{Error: "insufficient_scope"})
+				return
+			}
+		}
+
+		c.Set("clientID", p.ClientID)
+		c.Set("scopes", p.Scopes)
+		c.Next()
 	}
+}
 
-	var body validateResponse
-	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.UserID == "" {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "bad_auth_response"})
+// userFromPath кладёт в контекст пользователя из пути, чтобы внутренние маршруты
+// переиспользовали пользовательские обработчики.
+func userFromPath(c *gin.Context) {
+	if _, err := uuid.Parse(c.Param("user_id")); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, dto.ErrorResponse{Error: "invalid_user_id"})
 		return
 	}
+	c.Set("userID", c.Param("user_id"))
+	c.Next()
+}
 
-	c.Set("userID", body.UserID)
+func (m *AuthMiddleware) verify(c *gin.Context) (authkit.Principal, bool) {
+	h := c.GetHeader("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "bearer ") {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "no_bearer"})
+		return authkit.Principal{}, false
+	}
 
-	c.Next()
+	p, err := m.verifier.Verify(c.Request.Context(), strings.TrimSpace(h[7:]))
+	if err != nil {
+		switch {
+		case errors.Is(err, authkit.ErrAuthUnavailable):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "auth_unavailable"})
+		case errors.Is(err, authkit.ErrBadAuthResponse):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "bad_auth_response"})
+		default:
+			c.AbortWithStatusJSON(http.StatusUnauthorized, dto.ErrorResponse{Error: "invalid_token"})
+		}
+		return authkit.Principal{}, false
+	}
+	return p, true
+}
+
+// RequireRoles пропускает пользователя, у которого есть хотя бы одна из ролей
+// (имена — authkit.Role*). Ставится после AuthMiddleware.Handle.
+func RequireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, _ := c.Get("roles")
+		have, _ := v.([]string)
+		for _, r := range have {
+			if slices.Contains(roles, r) {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, dto.ErrorResponse{Error: "forbidden"})
+	}
 }
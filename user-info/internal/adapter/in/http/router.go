@@ -6,6 +6,7 @@
 package httpin
 
 import (
+	"github.com/EnduranNSU/authkit"
 	"github.com/gin-gonic/gin"
 
 	_ "github.com/EnduranNSU/end-user-info/docs"
@@ -18,7 +19,7 @@ import (
 // @version 1.0
 // @description Сервис информации о пользователе (вес, рост, возраст и т.д.)
 // @BasePath /api/v1
-func NewGinRouter(h *UserInfoHandler, authBaseURL string) *gin.Engine {
+func NewGinRouter(h *UserInfoHandler, auth AuthMiddlewareConfig) *gin.Engine {
 	r := gin.New()
 	r.Use(gin.Logger(), gin.Recovery())
 
@@ -26,16 +27,33 @@ func NewGinRouter(h *UserInfoHandler, authBaseURL string) *gin.Engine {
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	authMW := NewAuthMiddleware(authBaseURL)
+	authMW := NewAuthMiddleware(auth)
 
 	api := r.Group("/api/v1")
-	api.Use(authMW.Handle)
+	api.Use(authMW.Handle, RequireScopeByMethod(ScopeUserInfoRead, ScopeUserInfoWrite))
 	{
 		api.POST("/user-info", h.Create)
 
 		api.GET("/user-info/latest", h.GetLatest)
 
 		api.GET("/user-info", h.List)
+
+		// Данные любого пользователя — только для администраторов и тренеров
+		staff := api.Group("/users/:user_id", RequireRoles(authkit.RoleAdmin, authkit.RoleCoach), userFromPath)
+		{
+			staff.GET("/user-info", h.List)
+			staff.GET("/user-info/latest", h.GetLatest)
+		}
+	}
+
+	// Внутренние маршруты для других сервисов (токены client_credentials)
+	internal := r.Group("/internal/v1", authMW.RequireService(ScopeUserInfoRead))
+	{
+		users := internal.Group("/users/:user_id", userFromPath)
+		{
+			users.GET("/user-info", h.List)
+			users.GET("/user-info/latest", h.GetLatest)
+		}
 	}
 
 	return r
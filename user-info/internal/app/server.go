@@ -14,22 +14,27 @@ import (
 )
 
 type Server struct {
-	Svc         svcuserinfo.Service
-	Addr        string
-	AuthBaseURL string
+	Svc  svcuserinfo.Service
+	Addr string
+	Auth AuthConfig
 }
 
-func SetupServer(svc svcuserinfo.Service, addr, authBaseURL string) *Server {
+func SetupServer(svc svcuserinfo.Service, addr string, auth AuthConfig) *Server {
 	return &Server{
-		Svc:         svc,
-		Addr:        addr,
-		AuthBaseURL: authBaseURL,
+		Svc:  svc,
+		Addr: addr,
+		Auth: auth,
 	}
 }
 
 func (s *Server) StartServer() error {
 	h := httpin.NewUserInfoHandler(s.Svc)
-	engine := httpin.NewGinRouter(h, s.AuthBaseURL)
+	engine := httpin.NewGinRouter(h, httpin.AuthMiddlewareConfig{
+		BaseURL: s.Auth.BaseURL,
+		Mode:    s.Auth.Mode,
+		Issuer:  s.Auth.Issuer,
+		JWKSTTL: s.Auth.JWKSTTL,
+	})
 
 	srv := &http.Server{
 		Addr:              s.Addr,